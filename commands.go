@@ -0,0 +1,210 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/auth"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/captcha"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/cas"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/config"
+	"github.com/joho/godotenv"
+)
+
+// subcommand 描述一个可通过首个命令行参数调用的辅助命令。
+type subcommand struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]subcommand{
+	"captcha-report": {
+		usage: "按 OCR 后端统计验证码识别准确率",
+		run:   runCaptchaReport,
+	},
+	"captcha-preprocess": {
+		usage: "对已收集的验证码批量执行预处理并输出中间结果，用于调参",
+		run:   runCaptchaPreprocess,
+	},
+	"session-export": {
+		usage: "导出已保存的会话 Cookie（cookies.txt 或浏览器扩展 JSON），便于在浏览器中继续操作",
+		run:   runSessionExport,
+	},
+	"session-import": {
+		usage: "导入在浏览器中手动登录后导出的 Cookie，之后启动时直接复用该会话",
+		run:   runSessionImport,
+	},
+}
+
+func runCaptchaReport(args []string) error {
+	_ = godotenv.Load()
+
+	fs := flag.NewFlagSet("captcha-report", flag.ContinueOnError)
+	dir := fs.String("dir", captchaDatasetDir(), "验证码数据集目录")
+	period := fs.String("period", captcha.PeriodDay, "统计周期: day/week/month/all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	samples, err := captcha.LoadSamples(*dir)
+	if err != nil {
+		return err
+	}
+	rows, err := captcha.BuildReport(samples, *period)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("数据集: %s, 样本数: %d\n", *dir, len(samples))
+	return captcha.WriteReport(os.Stdout, rows)
+}
+
+func runCaptchaPreprocess(args []string) error {
+	_ = godotenv.Load()
+
+	fs := flag.NewFlagSet("captcha-preprocess", flag.ContinueOnError)
+	spec := fs.String("spec", strings.TrimSpace(os.Getenv("CAPTCHA_PREPROCESS")), "预处理滤镜链，例如 grayscale,median:1,binarize:otsu,delines:1")
+	in := fs.String("in", filepath.Join(captchaDatasetDir(), "images"), "验证码图片目录")
+	out := fs.String("out", "data/captcha-preprocess", "中间结果输出目录")
+	limit := fs.Int("limit", 50, "最多处理的图片数量，0 表示不限")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *spec == "" {
+		return fmt.Errorf("未指定预处理滤镜链（-spec 或 CAPTCHA_PREPROCESS）")
+	}
+
+	filters, err := captcha.ParseFilters(*spec)
+	if err != nil {
+		return err
+	}
+	pipeline := captcha.NewPipeline(filters, "")
+
+	entries, err := os.ReadDir(*in)
+	if err != nil {
+		return fmt.Errorf("读取验证码目录失败: %w", err)
+	}
+
+	processed := 0
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if *limit > 0 && processed >= *limit {
+			break
+		}
+
+		data, err := os.ReadFile(filepath.Join(*in, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("读取验证码图片失败: %w", err)
+		}
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if _, err := pipeline.Dump(data, filepath.Join(*out, name)); err != nil {
+			fmt.Printf("跳过 %s: %v\n", entry.Name(), err)
+			continue
+		}
+		processed++
+	}
+
+	fmt.Printf("已处理 %d 张验证码，中间结果输出到: %s\n", processed, *out)
+	return nil
+}
+
+func runSessionExport(args []string) error {
+	fs := flag.NewFlagSet("session-export", flag.ContinueOnError)
+	format := fs.String("format", cas.CookieFormatNetscape, "导出格式: netscape/json")
+	out := fs.String("o", "-", "输出文件，- 表示标准输出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client, err := newSessionClient()
+	if err != nil {
+		return err
+	}
+	if !client.LoadSession() {
+		return fmt.Errorf("没有可导出的 session，请先运行一次监控完成登录")
+	}
+
+	w := os.Stdout
+	if *out != "-" {
+		f, err := os.OpenFile(*out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if err != nil {
+			return fmt.Errorf("创建输出文件失败: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	n, err := client.ExportCookies(w, *format)
+	if err != nil {
+		return err
+	}
+	if *out != "-" {
+		fmt.Printf("已导出 %d 个 Cookie 到: %s\n", n, *out)
+	}
+	return nil
+}
+
+func runSessionImport(args []string) error {
+	fs := flag.NewFlagSet("session-import", flag.ContinueOnError)
+	format := fs.String("format", cas.CookieFormatAuto, "导入格式: auto/netscape/json")
+	in := fs.String("in", "-", "Cookie 文件，- 表示标准输入")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client, err := newSessionClient()
+	if err != nil {
+		return err
+	}
+
+	r := os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return fmt.Errorf("打开 Cookie 文件失败: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	n, err := client.ImportCookies(r, *format)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已导入 %d 个 Cookie，下次启动时将优先复用该会话\n", n)
+	return nil
+}
+
+// newSessionClient 按配置创建只用于读写 session 文件的 CAS 客户端
+func newSessionClient() (*cas.Client, error) {
+	cfg, err := config.LoadSession()
+	if err != nil {
+		return nil, fmt.Errorf("配置加载失败: %w", err)
+	}
+
+	opts := []cas.ClientOption{
+		cas.WithDataDir(cfg.DataDir),
+		cas.WithAccount(cfg.Username),
+	}
+	if cfg.SessionKey != "" {
+		key, err := auth.ParseKey(cfg.SessionKey)
+		if err != nil {
+			return nil, fmt.Errorf("SESSION_KEY 无效: %w", err)
+		}
+		opts = append(opts, cas.WithSessionKey(key))
+	}
+	return cas.NewClient(opts...)
+}
+
+// captchaDatasetDir 返回验证码数据集目录，未配置时使用 data/captcha。
+func captchaDatasetDir() string {
+	if dir := strings.TrimSpace(os.Getenv("CAPTCHA_DATASET_DIR")); dir != "" {
+		return dir
+	}
+	return "data/captcha"
+}
@@ -4,20 +4,35 @@ import (
 	"context"
 	"flag"
 	"log"
-	"net/http"
+	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/auth"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/bot"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/captcha"
 	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/cas"
 	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/config"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/har"
 	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/jwxt"
 	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/logger"
 	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/monitor"
 	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/notify"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/webvpn"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				log.Fatalf("[ERROR] %s 执行失败: %v", os.Args[1], err)
+			}
+			return
+		}
+	}
+
 	cleanupLogger, err := logger.Init()
 	if err != nil {
 		log.Fatalf("[ERROR] 初始化日志器失败: %v", err)
@@ -30,12 +45,104 @@ func main() {
 	}
 
 	timeout := flag.Duration("t", 30*time.Second, "请求超时时间")
+	loop := flag.Bool("loop", false, "持续轮询模式，按 POLL_INTERVAL 间隔执行并启用会话保活")
 	flag.Parse()
 
 	log.Printf("[INFO] 启动配置: username=%s onebot=%s groups=%d courses=%d ocr_api=%s",
 		cfg.Username, cfg.OneBotURL, len(cfg.GroupList), len(cfg.CourseList), cfg.OCRApiURL)
 
-	casClient, err := cas.NewClient(cas.WithTimeout(*timeout))
+	clientOpts := []cas.ClientOption{
+		cas.WithTimeout(*timeout),
+		cas.WithDataDir(cfg.DataDir),
+		cas.WithAccount(cfg.Username),
+		cas.WithConnectionPool(cfg.MaxIdleConns, cfg.MaxIdleConnsPerHost, cfg.MaxConnsPerHost),
+		cas.WithLoginLimit(cfg.LoginMaxFailures, time.Duration(cfg.LoginFailureWindow)*time.Minute),
+	}
+	if cfg.ProxyURL != "" {
+		clientOpts = append(clientOpts, cas.WithProxy(cfg.ProxyURL))
+		log.Printf("[INFO] 通过代理访问教务系统: %s", cfg.ProxyURL)
+	}
+	if cfg.TLSCAFile != "" || cfg.TLSServerName != "" || cfg.TLSInsecureSkipVerify {
+		tlsConfig, err := cas.LoadTLSConfig(cfg.TLSCAFile, cfg.TLSServerName, cfg.TLSInsecureSkipVerify)
+		if err != nil {
+			log.Fatalf("[ERROR] 加载 TLS 配置失败: %v", err)
+		}
+		clientOpts = append(clientOpts, cas.WithTLSConfig(tlsConfig))
+	}
+	if cfg.UserAgent != "" {
+		clientOpts = append(clientOpts, cas.WithUserAgent(cfg.UserAgent))
+	}
+
+	var vpn *webvpn.Transport
+	if cfg.WebVPNEnabled {
+		vpn, err = webvpn.New(webvpn.Config{
+			GatewayURL: cfg.WebVPNURL,
+			Username:   cfg.WebVPNUsername,
+			Password:   cfg.WebVPNPassword,
+			Ticket:     cfg.WebVPNTicket,
+			Timeout:    *timeout,
+		})
+		if err != nil {
+			log.Fatalf("[ERROR] 初始化 WebVPN 失败: %v", err)
+		}
+		clientOpts = append(clientOpts, cas.WithTransportWrapper(vpn.Wrap))
+	}
+	if cfg.HARDir != "" {
+		// 在 WebVPN 之后添加，使记录的是改写前的教务系统 URL
+		recorder, err := har.New(har.Config{
+			Dir:      cfg.HARDir,
+			MaxFiles: cfg.HARMaxFiles,
+			MaxBytes: int64(cfg.HARMaxMB) << 20,
+		})
+		if err != nil {
+			log.Fatalf("[ERROR] 初始化 HAR 记录失败: %v", err)
+		}
+		defer recorder.Close()
+		clientOpts = append(clientOpts, cas.WithTransportWrapper(recorder.Wrap))
+		log.Printf("[INFO] 已启用 HAR 流量记录（已脱敏）: %s", cfg.HARDir)
+	}
+	if cfg.SessionKey != "" {
+		key, err := auth.ParseKey(cfg.SessionKey)
+		if err != nil {
+			log.Fatalf("[ERROR] SESSION_KEY 无效: %v", err)
+		}
+		clientOpts = append(clientOpts, cas.WithSessionKey(key))
+	} else {
+		log.Printf("[WARN] 未配置 SESSION_KEY 或 SESSION_KEY_FILE，session 将以明文存储")
+	}
+	if cfg.CaptchaDatasetDir != "" {
+		recorder, err := captcha.NewRecorder(cfg.CaptchaDatasetDir)
+		if err != nil {
+			log.Fatalf("[ERROR] 初始化验证码数据集失败: %v", err)
+		}
+		clientOpts = append(clientOpts, cas.WithCaptchaRecorder(recorder))
+		log.Printf("[INFO] 已启用验证码数据集记录: %s", cfg.CaptchaDatasetDir)
+	}
+
+	if cfg.CaptchaPreprocess != "" {
+		filters, err := captcha.ParseFilters(cfg.CaptchaPreprocess)
+		if err != nil {
+			log.Fatalf("[ERROR] 解析验证码预处理配置失败: %v", err)
+		}
+		pipeline := captcha.NewPipeline(filters, cfg.CaptchaPreprocessDebugDir)
+		clientOpts = append(clientOpts, cas.WithCaptchaPreprocessor(pipeline))
+		log.Printf("[INFO] 已启用验证码预处理: %s", cfg.CaptchaPreprocess)
+	}
+
+	validator := captcha.DefaultValidator()
+	if cfg.CaptchaAlphabet != "" {
+		validator.Alphabet = cfg.CaptchaAlphabet
+	}
+	if cfg.CaptchaLength > 0 {
+		validator.Length = cfg.CaptchaLength
+	}
+	clientOpts = append(clientOpts, cas.WithCaptchaValidator(validator))
+	// 未设置时使用 cas.MaxCaptchaRefreshes
+	if cfg.CaptchaMaxRefresh >= 0 {
+		clientOpts = append(clientOpts, cas.WithCaptchaRefreshLimit(cfg.CaptchaMaxRefresh))
+	}
+
+	casClient, err := cas.NewClient(clientOpts...)
 	if err != nil {
 		log.Fatalf("[ERROR] 初始化 CAS 客户端失败: %v", err)
 	}
@@ -43,8 +150,26 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	if vpn != nil {
+		if err := vpn.EnsureLogin(ctx); err != nil {
+			log.Fatalf("[ERROR] WebVPN 登录失败: %v", err)
+		}
+		log.Printf("[INFO] 已启用 WebVPN，教务请求将经由网关转发")
+	}
+
 	// 创建 OCR 客户端
-	ocrClient := cas.NewDefaultOCRClient(cfg.OCRApiURL)
+	ocrClient := cas.NewHTTPOCRClient(cas.OCRConfig{
+		APIURL:       cfg.OCRApiURL,
+		Mode:         cfg.OCRMode,
+		Endpoint:     cfg.OCREndpoint,
+		ImageField:   cfg.OCRImageField,
+		TextField:    cfg.OCRTextField,
+		SuccessField: cfg.OCRSuccessField,
+		SuccessValue: cfg.OCRSuccessValue,
+		MessageField: cfg.OCRMessageField,
+		Timeout:      cfg.OCRTimeout,
+		Name:         cfg.OCRName,
+	})
 
 	log.Printf("[INFO] 正在登录教务系统: %s", cfg.Username)
 	startTime := time.Now()
@@ -61,33 +186,79 @@ func main() {
 		}
 	}()
 
-	roundID, err := jwxt.GetSelectionRoundID(ctx, casClient.GetClient())
-	if err != nil {
-		if jwxt.IsSelectionRoundNotFound(err) {
-			log.Printf("[WARN] 未找到可用选课轮次，跳过轮次进入并直接执行搜索: %v", err)
-		} else {
-			log.Fatalf("[ERROR] 获取选课轮次失败: %v", err)
-		}
-	} else {
-		if err := jwxt.EnterSelectionRound(ctx, casClient.GetClient(), roundID); err != nil {
-			log.Fatalf("[ERROR] 进入选课轮次失败: %v", err)
+	var (
+		store  *bot.Store
+		router notify.Router
+	)
+	if cfg.BotEnabled {
+		store, err = bot.OpenStore(filepath.Join(casClient.DataDir(), bot.SubscriptionsFileName))
+		if err != nil {
+			log.Fatalf("[ERROR] 加载订阅失败: %v", err)
 		}
-		log.Printf("[INFO] 已进入选课轮次: %s", roundID)
+		router = store
 	}
 
-	notifier := notify.NewNotifier(
-		cfg.OneBotURL,
-		cfg.OneBotToken,
-		cfg.GroupList,
-		&http.Client{Timeout: 10 * time.Second},
-	)
+	notifier, onebot, err := newNotifier(cfg, router)
+	if err != nil {
+		log.Fatalf("[ERROR] 初始化推送渠道失败: %v", err)
+	}
+	defer notifier.Close()
 
-	worker, err := monitor.New(casClient, cfg, notifier)
+	monitorOpts := []monitor.Option{
+		monitor.WithOCRClient(ocrClient),
+		monitor.WithDataDir(cfg.DataDir),
+	}
+	if store != nil {
+		monitorOpts = append(monitorOpts, monitor.WithCourseSource(store))
+	}
+	worker, err := monitor.New(casClient, cfg, notifier, monitorOpts...)
 	if err != nil {
 		log.Fatalf("[ERROR] 创建监控器失败: %v", err)
 	}
 
-	if err := worker.Run(ctx); err != nil {
+	if cfg.CommandsEnabled() && *loop {
+		botOpts := []bot.Option{
+			bot.WithGroups(cfg.GroupList),
+			bot.WithMaxSubscriptions(cfg.BotMaxSubscriptions),
+			bot.WithMaxTotalSubscriptions(cfg.BotMaxTotal),
+			bot.WithQueryCooldown(time.Duration(cfg.BotQueryCooldown)*time.Second, time.Duration(cfg.BotGlobalCooldown)*time.Second),
+		}
+		if store != nil {
+			botOpts = append(botOpts, bot.WithSubscriptions(store, worker))
+			log.Printf("[INFO] 订阅指令已启用，当前订阅课程=%d", len(store.Courses()))
+		}
+		if len(cfg.AdminList) > 0 {
+			botOpts = append(botOpts, bot.WithAdmins(cfg.AdminList, worker))
+			log.Printf("[INFO] 管理员指令已启用，管理员=%d", len(cfg.AdminList))
+		}
+		server, err := startBot(cfg, bot.New(onebot, botOpts...), onebot)
+		if err != nil {
+			log.Fatalf("[ERROR] 启动机器人失败: %v", err)
+		}
+		if server != nil {
+			defer server.Close()
+		}
+	} else if cfg.CommandsEnabled() {
+		log.Printf("[WARN] 已配置 BOT_ENABLED 或 ADMIN_LIST，但单次执行模式不接收 QQ 指令，需使用 -loop 启动")
+	}
+
+	if err := worker.EnterRound(ctx); err != nil {
+		if !*loop || !jwxt.IsRoundUnavailable(err) {
+			log.Fatalf("[ERROR] %v", err)
+		}
+		log.Printf("[WARN] 选课轮次暂不可用，将在每轮查询前重新尝试进入: %v", err)
+	}
+
+	if *loop {
+		casClient.StartKeepalive(ctx, cas.KeepaliveConfig{
+			Interval: time.Duration(cfg.Keepalive) * time.Second,
+			Relogin:  worker.Relogin,
+		})
+		err = worker.RunLoop(ctx)
+	} else {
+		err = worker.Run(ctx)
+	}
+	if err != nil {
 		log.Fatalf("[ERROR] 监控异常退出: %v", err)
 	}
 	log.Printf("[INFO] 程序已退出")
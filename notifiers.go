@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/bot"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/config"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/notify"
+)
+
+// newNotifier 按 NOTIFY_CHANNELS 创建各推送渠道，并由 Dispatcher 并行推送。
+// 消息模板在此加载，模板文件不存在或语法错误时启动失败；图片推送初始化失败时退回文本。
+// router 非空时 OneBot 只广播 COURSE_LIST 中的课程，订阅的课程按 router 定向推送。
+// 未启用 onebot 渠道时返回的 *notify.OneBotNotifier 为 nil。
+func newNotifier(cfg *config.Config, router notify.Router) (*notify.Dispatcher, *notify.OneBotNotifier, error) {
+	renderer, err := notify.NewRenderer(map[string]string{
+		notify.FormatText:     cfg.TemplateText,
+		notify.FormatMarkdown: cfg.TemplateMarkdown,
+		notify.FormatHTML:     cfg.TemplateHTML,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var onebot *notify.OneBotNotifier
+	notifiers := make([]notify.Notifier, 0, len(cfg.NotifyChannels))
+	for _, channel := range cfg.NotifyChannels {
+		switch channel {
+		case config.NotifyChannelOneBot:
+			transport, err := newOneBotTransport(cfg)
+			if err != nil {
+				return nil, nil, err
+			}
+			opts := []notify.OneBotOption{
+				notify.WithPrivateList(cfg.PrivateList),
+				notify.WithCourseWatchers(cfg.CourseWatchers),
+				notify.WithRenderer(renderer),
+				notify.WithMaxMessageLength(cfg.OneBotMaxLength),
+				notify.WithRateLimit(cfg.OneBotRateLimit, cfg.OneBotTargetRateLimit),
+				notify.WithRetry(cfg.OneBotMaxRetries, cfg.OneBotRetryRetCodes),
+			}
+			if cfg.OneBotImage {
+				// 图片只是文本推送的替代形式，字体不可用时退回文本，不影响监控启动
+				if imageRenderer, err := notify.NewImageRenderer(cfg.OneBotImageFont); err != nil {
+					log.Printf("[WARN] 初始化图片推送失败，将以文本推送: %v", err)
+				} else {
+					opts = append(opts, notify.WithImageRenderer(imageRenderer))
+				}
+			}
+			if router != nil {
+				opts = append(opts, notify.WithBroadcastCourses(cfg.CourseList), notify.WithRouter(router))
+			}
+			onebot = notify.NewOneBotNotifier(transport, cfg.GroupList, opts...)
+			notifiers = append(notifiers, onebot)
+		case config.NotifyChannelEmail:
+			notifiers = append(notifiers, newEmailNotifier(cfg, renderer))
+		default:
+			return nil, nil, fmt.Errorf("不支持的推送渠道: %s", channel)
+		}
+	}
+	return notify.NewDispatcher(notifiers...), onebot, nil
+}
+
+// newEmailNotifier 按 SMTP_* 与 EMAIL_* 配置创建邮件推送器
+func newEmailNotifier(cfg *config.Config, renderer *notify.Renderer) *notify.EmailNotifier {
+	server := notify.EmailServer{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		Security: cfg.SMTPSecurity,
+	}
+	recipients := make([]notify.EmailRecipient, 0, len(cfg.EmailRecipients))
+	for _, r := range cfg.EmailRecipients {
+		recipients = append(recipients, notify.EmailRecipient{Address: r.Address, Courses: r.Courses})
+	}
+	return notify.NewEmailNotifier(server, cfg.EmailFrom, recipients, notify.WithEmailRenderer(renderer))
+}
+
+// newOneBotTransport 按 ONEBOT_MODE 创建 OneBot API 调用方式
+func newOneBotTransport(cfg *config.Config) (notify.OneBotTransport, error) {
+	switch cfg.OneBotMode {
+	case config.OneBotModeWS:
+		return notify.NewWSTransport(cfg.OneBotURL, cfg.OneBotToken), nil
+	case config.OneBotModeReverseWS:
+		return notify.NewReverseWSTransport(cfg.OneBotListen, cfg.OneBotToken)
+	default:
+		return notify.NewHTTPTransport(cfg.OneBotURL, cfg.OneBotToken, &http.Client{Timeout: 10 * time.Second}), nil
+	}
+}
+
+// startBot 将 OneBot 事件交给机器人处理。WebSocket 模式复用现有连接接收事件，
+// http 模式在 ONEBOT_EVENT_LISTEN 上监听事件上报，返回的 EventServer 需在退出时关闭。
+func startBot(cfg *config.Config, b *bot.Bot, onebot *notify.OneBotNotifier) (*notify.EventServer, error) {
+	if source, ok := onebot.Transport().(notify.EventSource); ok {
+		source.OnEvent(b.HandleEvent)
+		return nil, nil
+	}
+
+	server, err := notify.NewEventServer(cfg.OneBotEventListen, cfg.OneBotSecret)
+	if err != nil {
+		return nil, err
+	}
+	server.OnEvent(b.HandleEvent)
+	return server, nil
+}
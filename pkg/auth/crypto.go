@@ -1,7 +1,15 @@
 package auth
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
 )
 
 // EncodeBase64 对字符串进行 Base64 编码
@@ -25,3 +33,70 @@ func GenerateEncoded(username, password string) string {
 	passwordBase64 := EncodeBase64(password)
 	return usernameBase64 + "%%%" + passwordBase64
 }
+
+// KeySize AES-256 密钥长度（字节）
+const KeySize = 32
+
+// ErrInvalidKey 密钥不是 32 字节随机密钥的编码
+var ErrInvalidKey = errors.New("密钥需为 32 字节随机数的 base64 或十六进制编码，可使用 openssl rand -base64 32 生成")
+
+// ParseKey 解析 base64 或十六进制编码的 32 字节 AES-256 密钥。
+// 不接受口令：口令未经加盐的慢哈希派生时，弱口令可以对着 session 文件离线暴力破解
+func ParseKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	if len(encoded) == hex.EncodedLen(KeySize) {
+		if key, err := hex.DecodeString(encoded); err == nil {
+			return key, nil
+		}
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := enc.DecodeString(encoded); err == nil && len(key) == KeySize {
+			return key, nil
+		}
+	}
+	return nil, ErrInvalidKey
+}
+
+// Encrypt 使用 AES-GCM 加密数据，输出格式: nonce + 密文
+func Encrypt(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt 解密 Encrypt 生成的数据，密钥错误或数据被篡改时返回错误
+func Decrypt(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("密文长度不足")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("解密失败（密钥错误或数据已损坏）: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("创建 AES 密钥失败: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("创建 GCM 失败: %w", err)
+	}
+	return gcm, nil
+}
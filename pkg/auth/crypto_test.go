@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	key := bytes.Repeat([]byte{0xab, 0x01, 0xfe, 0x7f}, KeySize/4)
+	tests := []struct {
+		name    string
+		encoded string
+		wantErr bool
+	}{
+		{"base64", base64.StdEncoding.EncodeToString(key), false},
+		{"base64 without padding", base64.RawStdEncoding.EncodeToString(key), false},
+		{"base64url", base64.URLEncoding.EncodeToString(key), false},
+		{"hex", hex.EncodeToString(key), false},
+		{"surrounding whitespace", " " + base64.StdEncoding.EncodeToString(key) + "\n", false},
+		{"passphrase", "correct horse battery staple", true},
+		{"short key", base64.StdEncoding.EncodeToString(key[:16]), true},
+		{"long key", base64.StdEncoding.EncodeToString(append(key, 0)), true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKey(tt.encoded)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidKey) {
+					t.Fatalf("ParseKey(%q) error = %v, want ErrInvalidKey", tt.encoded, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseKey(%q) error = %v", tt.encoded, err)
+			}
+			if !bytes.Equal(got, key) {
+				t.Errorf("ParseKey(%q) = %x, want %x", tt.encoded, got, key)
+			}
+		})
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, KeySize)
+	plaintext := []byte(`[{"Name":"JSESSIONID"}]`)
+
+	data, err := Encrypt(key, plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	got, err := Decrypt(key, data)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("Decrypt() = %q, want %q", got, plaintext)
+	}
+
+	tampered := bytes.Clone(data)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := Decrypt(key, tampered); err == nil {
+		t.Error("Decrypt(tampered) error = nil, want error")
+	}
+	if _, err := Decrypt(bytes.Repeat([]byte{2}, KeySize), data); err == nil {
+		t.Error("Decrypt(wrong key) error = nil, want error")
+	}
+	if _, err := Decrypt(key, data[:4]); err == nil {
+		t.Error("Decrypt(truncated) error = nil, want error")
+	}
+}
@@ -0,0 +1,143 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/config"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/monitor"
+)
+
+// adminTimeout 管理员指令的处理超时，重登与立即检查可能需要识别多次验证码
+const adminTimeout = 3 * time.Minute
+
+// Controller 远程控制监控所需的能力，由 monitor.Monitor 实现
+type Controller interface {
+	Status() monitor.Status
+	Pause() bool
+	Resume() bool
+	ForceRelogin(ctx context.Context) error
+	ReloadConfig() (*config.Config, error)
+	CheckNow(ctx context.Context) (monitor.RoundResult, error)
+}
+
+// adminCommands 管理员指令
+var adminCommands = []string{"状态", "暂停", "恢复", "重登", "重载配置", "立即检查"}
+
+const adminHelp = `管理员指令：
+状态：查看最近一轮检查、错误与会话时长
+暂停 / 恢复：暂停或恢复轮询
+重登：立即重新登录教务系统
+重载配置：重新读取 .env 中的课程、模块与轮询间隔
+立即检查：立即执行一轮查询并推送`
+
+func isAdminCommand(name string) bool {
+	for _, cmd := range adminCommands {
+		if cmd == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Bot) isAdmin(user string) bool {
+	return b.controller != nil && b.admins[user]
+}
+
+func (b *Bot) executeAdmin(ctx context.Context, cmd command) string {
+	switch cmd.name {
+	case "状态":
+		return formatStatus(b.controller.Status())
+	case "暂停":
+		if !b.controller.Pause() {
+			return "监控已处于暂停状态"
+		}
+		return "监控已暂停，会话保活仍在运行，发送“恢复”继续轮询"
+	case "恢复":
+		if !b.controller.Resume() {
+			return "监控未暂停"
+		}
+		return "监控已恢复"
+	case "重登":
+		if err := b.controller.ForceRelogin(ctx); err != nil {
+			return fmt.Sprintf("重新登录失败：%v", err)
+		}
+		return "重新登录成功"
+	case "重载配置":
+		cfg, err := b.controller.ReloadConfig()
+		if err != nil {
+			return fmt.Sprintf("重载配置失败：%v", err)
+		}
+		return fmt.Sprintf("配置已重载：课程关键词 %d 个，轮询间隔 %d 秒。推送渠道、OneBot 与账号相关配置需重启后生效",
+			len(cfg.CourseList), cfg.PollInterval)
+	case "立即检查":
+		result, err := b.controller.CheckNow(ctx)
+		if err != nil {
+			return fmt.Sprintf("检查失败：%v", err)
+		}
+		return "检查完成：" + formatRound(result)
+	default:
+		return adminHelp
+	}
+}
+
+func formatStatus(status monitor.Status) string {
+	var sb strings.Builder
+	sb.WriteString("【选课监控】运行状态")
+
+	state := "运行中"
+	if status.Paused {
+		state = "已暂停"
+	}
+	sb.WriteString(fmt.Sprintf("\n监控：%s，关键词 %d 个，间隔 %s", state, status.Keywords, status.PollInterval))
+
+	switch {
+	case status.RoundErr != nil:
+		sb.WriteString(fmt.Sprintf("\n轮次：暂不可用（%v）", status.RoundErr))
+	case status.Round != "":
+		sb.WriteString("\n轮次：" + status.Round)
+	default:
+		sb.WriteString("\n轮次：未进入")
+	}
+
+	if status.LastRound.At.IsZero() {
+		sb.WriteString("\n上轮检查：尚未执行")
+	} else {
+		sb.WriteString(fmt.Sprintf("\n上轮检查：%s，%s",
+			status.LastRound.At.Format("01-02 15:04:05"), formatRound(status.LastRound)))
+	}
+
+	if status.LastError != nil {
+		sb.WriteString(fmt.Sprintf("\n最近错误：%s %v", status.LastErrorAt.Format("01-02 15:04:05"), status.LastError))
+	} else {
+		sb.WriteString("\n最近错误：无")
+	}
+
+	if status.Outbox > 0 {
+		sb.WriteString(fmt.Sprintf("\n待重发推送：%d 批", status.Outbox))
+	}
+
+	if status.SessionAge > 0 {
+		sb.WriteString("\n会话时长：" + status.SessionAge.Round(time.Second).String())
+	} else {
+		sb.WriteString("\n会话时长：未知")
+	}
+	return sb.String()
+}
+
+func formatRound(result monitor.RoundResult) string {
+	duration := result.Duration.Round(time.Millisecond)
+	switch {
+	case result.Err != nil:
+		return fmt.Sprintf("已跳过（%v），耗时 %s", result.Err, duration)
+	case result.Baseline:
+		return fmt.Sprintf("建立首轮基线，课程 %d 个，耗时 %s", result.Courses, duration)
+	case result.NotifyErr != nil:
+		return fmt.Sprintf("课程 %d 个，余量增加 %d 个，推送失败（%v），耗时 %s",
+			result.Courses, result.Increased, result.NotifyErr, duration)
+	default:
+		return fmt.Sprintf("课程 %d 个，余量增加 %d 个，耗时 %s", result.Courses, result.Increased, duration)
+	}
+}
@@ -0,0 +1,385 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/jwxt"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/notify"
+)
+
+const (
+	// DefaultMaxSubscriptions 每个用户默认最多订阅的课程数
+	DefaultMaxSubscriptions = 5
+	// DefaultMaxTotalSubscriptions 全部用户默认最多订阅的总数，每个订阅课程每轮都要查询一次教务系统
+	DefaultMaxTotalSubscriptions = 100
+	// DefaultQueryCooldown 同一用户两次查询之间的默认间隔
+	DefaultQueryCooldown = 30 * time.Second
+	// DefaultGlobalQueryCooldown 任意两次查询之间的默认间隔，避免多人同时查询造成教务系统压力
+	DefaultGlobalQueryCooldown = 5 * time.Second
+
+	// maxKeywordRunes 订阅与查询关键词的最大长度
+	maxKeywordRunes = 32
+	// maxQueryResults 查询指令最多展示的课程数
+	maxQueryResults = 10
+	// handleTimeout 单条指令的处理超时（包含查询教务系统与回复）
+	handleTimeout = 30 * time.Second
+)
+
+// Sender 回复消息所需的 OneBot 能力，由 notify.OneBotNotifier 实现
+type Sender interface {
+	SendGroupMessage(ctx context.Context, groupID string, message notify.Message) error
+	SendPrivateMessage(ctx context.Context, userID string, message notify.Message) error
+}
+
+// Searcher 查询课程，由 monitor.Monitor 实现
+type Searcher interface {
+	SearchCourses(ctx context.Context, keyword string) ([]jwxt.CourseInfo, error)
+}
+
+// Bot 解析并执行 QQ 消息中的指令
+type Bot struct {
+	store            *Store
+	sender           Sender
+	searcher         Searcher
+	groups           map[string]bool
+	maxSubscriptions int
+	maxTotal         int
+	queries          *cooldown
+
+	admins     map[string]bool
+	controller Controller
+}
+
+// Option 机器人配置项
+type Option func(*Bot)
+
+// WithSubscriptions 启用订阅指令，searcher 用于查询指令
+func WithSubscriptions(store *Store, searcher Searcher) Option {
+	return func(b *Bot) {
+		b.store = store
+		b.searcher = searcher
+	}
+}
+
+// WithAdmins 启用管理员指令，仅 admins 中的 QQ 号可以使用
+func WithAdmins(admins []string, controller Controller) Option {
+	return func(b *Bot) {
+		b.admins = make(map[string]bool, len(admins))
+		for _, admin := range admins {
+			b.admins[admin] = true
+		}
+		b.controller = controller
+	}
+}
+
+// WithGroups 限定响应指令的群，未设置时响应所有群
+func WithGroups(groups []string) Option {
+	return func(b *Bot) {
+		if len(groups) == 0 {
+			return
+		}
+		b.groups = make(map[string]bool, len(groups))
+		for _, group := range groups {
+			b.groups[group] = true
+		}
+	}
+}
+
+// WithMaxSubscriptions 设置每个用户最多订阅的课程数
+func WithMaxSubscriptions(n int) Option {
+	return func(b *Bot) {
+		if n > 0 {
+			b.maxSubscriptions = n
+		}
+	}
+}
+
+// WithMaxTotalSubscriptions 设置全部用户最多订阅的总数
+func WithMaxTotalSubscriptions(n int) Option {
+	return func(b *Bot) {
+		if n > 0 {
+			b.maxTotal = n
+		}
+	}
+}
+
+// WithQueryCooldown 设置查询指令的冷却时间：perUser 为同一用户两次查询的间隔，
+// global 为任意两次查询的间隔，为 0 时不限制
+func WithQueryCooldown(perUser, global time.Duration) Option {
+	return func(b *Bot) {
+		b.queries = newCooldown(perUser, global)
+	}
+}
+
+// New 创建机器人，通过 WithSubscriptions 与 WithAdmins 启用对应的指令
+func New(sender Sender, opts ...Option) *Bot {
+	b := &Bot{
+		sender:           sender,
+		maxSubscriptions: DefaultMaxSubscriptions,
+		maxTotal:         DefaultMaxTotalSubscriptions,
+		queries:          newCooldown(DefaultQueryCooldown, DefaultGlobalQueryCooldown),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// command 一条解析后的指令
+type command struct {
+	name string
+	arg  string
+}
+
+// subscriptionCommands 订阅指令，较长的指令需排在其前缀之前
+var subscriptionCommands = []string{"取消订阅", "我的订阅", "订阅", "查询", "帮助"}
+
+// HandleEvent 处理 OneBot 事件，可直接作为 notify.EventHandler 使用
+func (b *Bot) HandleEvent(event *notify.OneBotEvent) {
+	if !event.IsMessage() || event.UserID == event.SelfID {
+		return
+	}
+	group := event.Group()
+	if group != "" && b.groups != nil && !b.groups[group] {
+		return
+	}
+
+	user := event.Sender()
+	text, mentionsSelf := event.Text()
+	names := b.commandNames(user)
+	if len(names) == 0 {
+		return
+	}
+	cmd, ok := parseCommand(text, names)
+	if !ok {
+		// 群里 @ 机器人但不是指令时回复帮助，其余消息一律忽略
+		if group == "" || !mentionsSelf {
+			return
+		}
+		cmd = command{name: "帮助"}
+	}
+
+	timeout := handleTimeout
+	if isAdminCommand(cmd.name) {
+		timeout = adminTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	log.Printf("[INFO] 收到机器人指令: user=%s group=%s cmd=%s %s", user, group, cmd.name, cmd.arg)
+	var reply string
+	if isAdminCommand(cmd.name) {
+		reply = b.executeAdmin(ctx, cmd)
+	} else {
+		reply = b.execute(ctx, cmd, user, group)
+	}
+	if err := b.reply(ctx, user, group, reply); err != nil {
+		log.Printf("[WARN] 回复机器人指令失败: %v", err)
+	}
+}
+
+// parseCommand 识别以指令开头的消息。指令与参数之间需有空白，
+// 参数为课程号这类字母数字时可省略空白（如“订阅A001”）
+func parseCommand(text string, names []string) (command, bool) {
+	text = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), "/"))
+	for _, name := range names {
+		rest, ok := strings.CutPrefix(text, name)
+		if !ok {
+			continue
+		}
+		arg := strings.TrimSpace(rest)
+		if rest != "" && !startsWithSpace(rest) && !isCourseCode(arg) {
+			return command{}, false
+		}
+		return command{name: name, arg: arg}, true
+	}
+	return command{}, false
+}
+
+func startsWithSpace(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsSpace(r)
+}
+
+// isCourseCode 判断是否为仅由字母、数字、- 与 _ 组成的课程号
+func isCourseCode(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *Bot) execute(ctx context.Context, cmd command, user, group string) string {
+	switch cmd.name {
+	case "订阅":
+		return b.subscribe(user, group, cmd.arg)
+	case "取消订阅":
+		return b.unsubscribe(user, cmd.arg)
+	case "我的订阅":
+		return b.listSubscriptions(user)
+	case "查询":
+		return b.query(ctx, user, cmd.arg)
+	default:
+		return b.helpText(user)
+	}
+}
+
+// commandNames 返回用户可用的指令，管理员指令排在前面
+func (b *Bot) commandNames(user string) []string {
+	var names []string
+	if b.isAdmin(user) {
+		names = append(names, adminCommands...)
+	}
+	if b.store != nil {
+		names = append(names, subscriptionCommands...)
+	} else if len(names) > 0 {
+		names = append(names, "帮助")
+	}
+	return names
+}
+
+func (b *Bot) helpText(user string) string {
+	var parts []string
+	if b.store != nil {
+		parts = append(parts, subscriptionHelp)
+	}
+	if b.isAdmin(user) {
+		parts = append(parts, adminHelp)
+	}
+	return "【选课监控】可用指令：\n" + strings.Join(parts, "\n")
+}
+
+const subscriptionHelp = `订阅 <课程号>：课程余量增加时提醒你
+取消订阅 <课程号>：取消指定订阅，不带课程号时取消全部
+我的订阅：查看已订阅的课程
+查询 <课程号>：查询课程当前余量`
+
+func (b *Bot) subscribe(user, group, course string) string {
+	if msg := validateKeyword(course); msg != "" {
+		return msg
+	}
+	existing := b.store.UserSubscriptions(user)
+	subscribed := false
+	for _, sub := range existing {
+		if sub.Course == course {
+			subscribed = true
+		}
+	}
+	if !subscribed && len(existing) >= b.maxSubscriptions {
+		return fmt.Sprintf("最多订阅 %d 门课程，请先取消不需要的订阅", b.maxSubscriptions)
+	}
+	if !subscribed && b.store.Len() >= b.maxTotal {
+		log.Printf("[WARN] 订阅总数已达上限 %d，拒绝用户 %s 订阅 %s", b.maxTotal, user, course)
+		return "订阅人数已满，暂时无法新增订阅，请稍后再试"
+	}
+
+	added, err := b.store.Subscribe(user, course, group)
+	if err != nil {
+		log.Printf("[ERROR] 保存订阅失败: %v", err)
+		return "订阅失败，请稍后再试"
+	}
+	where := "私聊"
+	if group != "" {
+		where = "本群"
+	}
+	if !added {
+		return fmt.Sprintf("已订阅过 %s，余量增加时将在%s提醒你", course, where)
+	}
+	return fmt.Sprintf("订阅成功：%s，余量增加时将在%s提醒你", course, where)
+}
+
+func (b *Bot) unsubscribe(user, course string) string {
+	removed, err := b.store.Unsubscribe(user, course)
+	if err != nil {
+		log.Printf("[ERROR] 保存订阅失败: %v", err)
+		return "取消订阅失败，请稍后再试"
+	}
+	switch {
+	case removed == 0 && course == "":
+		return "你还没有订阅任何课程"
+	case removed == 0:
+		return fmt.Sprintf("你没有订阅 %s", course)
+	case course == "":
+		return fmt.Sprintf("已取消全部 %d 个订阅", removed)
+	default:
+		return fmt.Sprintf("已取消订阅：%s", course)
+	}
+}
+
+func (b *Bot) listSubscriptions(user string) string {
+	subs := b.store.UserSubscriptions(user)
+	if len(subs) == 0 {
+		return "你还没有订阅任何课程，发送“订阅 <课程号>”开始订阅"
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("你已订阅 %d 门课程：", len(subs)))
+	for _, sub := range subs {
+		where := "私聊提醒"
+		if sub.GroupID != "" {
+			where = "群 " + sub.GroupID + " 提醒"
+		}
+		sb.WriteString(fmt.Sprintf("\n%s（%s）", sub.Course, where))
+	}
+	return sb.String()
+}
+
+func (b *Bot) query(ctx context.Context, user, keyword string) string {
+	if msg := validateKeyword(keyword); msg != "" {
+		return msg
+	}
+	if wait := b.queries.take(user, time.Now()); wait > 0 {
+		return fmt.Sprintf("查询过于频繁，请 %d 秒后再试", int(math.Ceil(wait.Seconds())))
+	}
+	courses, err := b.searcher.SearchCourses(ctx, keyword)
+	if err != nil {
+		log.Printf("[WARN] 机器人查询课程[%s]失败: %v", keyword, err)
+		return "查询失败，请稍后再试"
+	}
+	if len(courses) == 0 {
+		return fmt.Sprintf("没有找到与 %s 相关的可选课程", keyword)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("【选课监控】%s 共 %d 个教学班", keyword, len(courses)))
+	for i, course := range courses {
+		if i == maxQueryResults {
+			sb.WriteString(fmt.Sprintf("\n……其余 %d 个未显示", len(courses)-maxQueryResults))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("\n%d. %s（%s）%s %s 剩余 %s",
+			i+1, course.Kcmc, course.Kch, course.Skls, course.Sksj, course.Syrs))
+	}
+	return sb.String()
+}
+
+// validateKeyword 校验课程关键词，不合法时返回提示
+func validateKeyword(keyword string) string {
+	if keyword == "" {
+		return "请在指令后附上课程号，例如：订阅 A001"
+	}
+	if utf8.RuneCountInString(keyword) > maxKeywordRunes {
+		return fmt.Sprintf("课程号过长，最多 %d 个字符", maxKeywordRunes)
+	}
+	return ""
+}
+
+// reply 群聊中 @ 指令发送者回复，私聊直接回复
+func (b *Bot) reply(ctx context.Context, user, group, text string) error {
+	if group == "" {
+		return b.sender.SendPrivateMessage(ctx, user, notify.TextMessage(text))
+	}
+	message := notify.Message{notify.AtSegment(user), notify.TextSegment(" " + text)}
+	return b.sender.SendGroupMessage(ctx, group, message)
+}
@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+// cooldown 按用户与全局限制指令频率
+type cooldown struct {
+	perUser time.Duration
+	global  time.Duration
+
+	mu         sync.Mutex
+	lastGlobal time.Time
+	last       map[string]time.Time
+}
+
+func newCooldown(perUser, global time.Duration) *cooldown {
+	return &cooldown{
+		perUser: max(perUser, 0),
+		global:  max(global, 0),
+		last:    make(map[string]time.Time),
+	}
+}
+
+// take 未处于冷却时记录本次使用并返回 0，否则返回还需等待的时间
+func (c *cooldown) take(user string, now time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	wait := c.global - now.Sub(c.lastGlobal)
+	if last, ok := c.last[user]; ok {
+		wait = max(wait, c.perUser-now.Sub(last))
+	}
+	if wait > 0 {
+		return wait
+	}
+
+	// 顺带清理已过冷却期的用户，避免记录无限增长
+	for u, last := range c.last {
+		if now.Sub(last) >= c.perUser {
+			delete(c.last, u)
+		}
+	}
+	c.lastGlobal = now
+	if c.perUser > 0 {
+		c.last[user] = now
+	}
+	return 0
+}
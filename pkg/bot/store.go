@@ -0,0 +1,219 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/notify"
+)
+
+// SubscriptionsFileName 订阅数据文件名，存放于账号数据目录
+const SubscriptionsFileName = "subscriptions.json"
+
+// Subscription 一个 QQ 用户对一个课程关键词的订阅
+type Subscription struct {
+	UserID    string    `json:"user_id"`
+	Course    string    `json:"course"`
+	GroupID   string    `json:"group_id,omitempty"` // 订阅所在的群，为空表示私聊订阅
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store 按 QQ 用户持久化课程订阅，实现 monitor.CourseSource 与 notify.Router
+type Store struct {
+	path string
+
+	mu   sync.RWMutex
+	subs []Subscription
+}
+
+// OpenStore 打开订阅文件，文件不存在时创建空的订阅列表
+func OpenStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("读取订阅文件失败: %w", err)
+	}
+
+	var data struct {
+		Subscriptions []Subscription `json:"subscriptions"`
+	}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("解析订阅文件失败: %w", err)
+	}
+	s.subs = data.Subscriptions
+	return s, nil
+}
+
+// Subscribe 添加订阅，同一用户重复订阅同一课程时更新推送位置。
+// 返回 false 表示该用户此前已订阅过此课程。
+func (s *Store) Subscribe(userID, course, groupID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, sub := range s.subs {
+		if sub.UserID == userID && sub.Course == course {
+			if sub.GroupID == groupID {
+				return false, nil
+			}
+			s.subs[i].GroupID = groupID
+			return false, s.saveLocked()
+		}
+	}
+	s.subs = append(s.subs, Subscription{
+		UserID:    userID,
+		Course:    course,
+		GroupID:   groupID,
+		CreatedAt: time.Now(),
+	})
+	return true, s.saveLocked()
+}
+
+// Unsubscribe 取消订阅，course 为空时取消该用户的全部订阅，返回取消的数量
+func (s *Store) Unsubscribe(userID, course string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := make([]Subscription, 0, len(s.subs))
+	for _, sub := range s.subs {
+		if sub.UserID == userID && (course == "" || sub.Course == course) {
+			continue
+		}
+		kept = append(kept, sub)
+	}
+	removed := len(s.subs) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	s.subs = kept
+	return removed, s.saveLocked()
+}
+
+// UserSubscriptions 返回用户的全部订阅，按订阅时间排列
+func (s *Store) UserSubscriptions(userID string) []Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var subs []Subscription
+	for _, sub := range s.subs {
+		if sub.UserID == userID {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// Len 返回全部用户的订阅总数
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.subs)
+}
+
+// Courses 返回全部被订阅的课程关键词，按首次订阅顺序去重
+func (s *Store) Courses() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var courses []string
+	for _, sub := range s.subs {
+		if !seen[sub.Course] {
+			seen[sub.Course] = true
+			courses = append(courses, sub.Course)
+		}
+	}
+	return courses
+}
+
+// Routes 按订阅计算推送目标：群订阅在所在群中 @ 订阅者，私聊订阅直接私聊。
+// 每个目标只包含其订阅命中的事件。
+func (s *Store) Routes(batch notify.Batch) []notify.Route {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	routes := make(map[string]*notify.Route)
+	var order []string
+	for _, sub := range s.subs {
+		key := "private:" + sub.UserID
+		if sub.GroupID != "" {
+			key = "group:" + sub.GroupID
+		}
+
+		for _, event := range batch.Events {
+			if !event.HasKeyword(sub.Course) {
+				continue
+			}
+			route, ok := routes[key]
+			if !ok {
+				route = &notify.Route{GroupID: sub.GroupID}
+				if sub.GroupID == "" {
+					route.UserID = sub.UserID
+				}
+				routes[key] = route
+				order = append(order, key)
+			}
+			addRouteEvent(route, event)
+			if sub.GroupID != "" {
+				addMention(route, sub.UserID)
+			}
+		}
+	}
+
+	sort.Strings(order)
+	result := make([]notify.Route, 0, len(order))
+	for _, key := range order {
+		result = append(result, *routes[key])
+	}
+	return result
+}
+
+func addRouteEvent(route *notify.Route, event notify.Event) {
+	key := event.Course.UniqueKey()
+	for _, existing := range route.Events {
+		if existing.Course.UniqueKey() == key {
+			return
+		}
+	}
+	route.Events = append(route.Events, event)
+}
+
+func addMention(route *notify.Route, userID string) {
+	for _, user := range route.Mentions {
+		if user == userID {
+			return
+		}
+	}
+	route.Mentions = append(route.Mentions, userID)
+}
+
+// saveLocked 原子写入订阅文件，调用方需持有写锁
+func (s *Store) saveLocked() error {
+	content, err := json.MarshalIndent(struct {
+		Subscriptions []Subscription `json:"subscriptions"`
+	}{s.subs}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化订阅失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("创建订阅目录失败: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0o600); err != nil {
+		return fmt.Errorf("写入订阅文件失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("替换订阅文件失败: %w", err)
+	}
+	return nil
+}
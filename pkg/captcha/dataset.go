@@ -0,0 +1,168 @@
+package captcha
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	samplesFileName = "samples.jsonl"
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^0-9A-Za-z]+`)
+
+// Outcome 表示验证码样本对应的登录结果。
+type Outcome string
+
+const (
+	OutcomeSuccess       Outcome = "success"        // 登录成功，验证码正确
+	OutcomeCaptchaError  Outcome = "captcha_error"  // 教务系统提示验证码错误
+	OutcomePasswordError Outcome = "password_error" // 验证码已通过校验，但密码错误
+	OutcomeRejected      Outcome = "rejected"       // 识别结果未通过字符集/长度校验，未提交
+	OutcomeUnknown       Outcome = "unknown"        // 其它错误，无法判断验证码是否正确
+)
+
+// Judged 返回该结果能否用于判断识别是否正确，以及识别是否正确。
+func (o Outcome) Judged() (correct bool, known bool) {
+	switch o {
+	case OutcomeSuccess, OutcomePasswordError:
+		return true, true
+	case OutcomeCaptchaError, OutcomeRejected:
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// Sample 是一条带标注的验证码样本。
+type Sample struct {
+	Time    time.Time `json:"time"`
+	Backend string    `json:"backend"`
+	Text    string    `json:"text"`
+	Outcome Outcome   `json:"outcome"`
+	Image   string    `json:"image"` // 相对数据集目录的图片文件名
+	Detail  string    `json:"detail,omitempty"`
+}
+
+// Recorder 将验证码图片与识别结果、登录结果一起保存为数据集。
+//
+// 目录结构:
+//
+//	<dir>/samples.jsonl                          每行一条 Sample
+//	<dir>/images/<时间>_<识别文本>_<结果>.<扩展名>
+type Recorder struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewRecorder 创建数据集记录器，目录不存在时自动创建。
+func NewRecorder(dir string) (*Recorder, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("数据集目录不能为空")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "images"), 0o755); err != nil {
+		return nil, fmt.Errorf("创建验证码数据集目录失败: %w", err)
+	}
+	return &Recorder{dir: dir}, nil
+}
+
+// Dir 返回数据集目录。
+func (r *Recorder) Dir() string {
+	return r.dir
+}
+
+// Record 保存一张验证码图片，并在索引文件中追加对应样本。
+// sample.Image 由记录器生成，调用方无需填写。
+func (r *Recorder) Record(sample Sample, image []byte) error {
+	if sample.Time.IsZero() {
+		sample.Time = time.Now()
+	}
+
+	name := fmt.Sprintf("%s_%s_%s%s",
+		sample.Time.Format("20060102-150405.000"),
+		nonEmptyLabel(unsafeFileChars.ReplaceAllString(sample.Text, ""), "empty"),
+		sample.Outcome,
+		ImageExt(image),
+	)
+	sample.Image = filepath.ToSlash(filepath.Join("images", name))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := os.WriteFile(filepath.Join(r.dir, sample.Image), image, 0o644); err != nil {
+		return fmt.Errorf("写入验证码图片失败: %w", err)
+	}
+
+	line, err := json.Marshal(sample)
+	if err != nil {
+		return fmt.Errorf("序列化验证码样本失败: %w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(r.dir, samplesFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("打开样本索引失败: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("写入样本索引失败: %w", err)
+	}
+	return nil
+}
+
+// LoadSamples 读取数据集目录中的全部样本，跳过无法解析的行。
+func LoadSamples(dir string) ([]Sample, error) {
+	f, err := os.Open(filepath.Join(dir, samplesFileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("数据集为空或目录不存在: %s", dir)
+		}
+		return nil, fmt.Errorf("打开样本索引失败: %w", err)
+	}
+	defer f.Close()
+
+	samples := make([]Sample, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var sample Sample
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil {
+			continue
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取样本索引失败: %w", err)
+	}
+	return samples, nil
+}
+
+// ImageExt 按图片内容返回文件扩展名，无法识别时返回 .bin
+func ImageExt(data []byte) string {
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/bmp":
+		return ".bmp"
+	default:
+		return ".bin"
+	}
+}
+
+func nonEmptyLabel(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,199 @@
+package captcha
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestRecorderRecordAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir)
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+
+	at := time.Date(2025, 9, 1, 9, 30, 0, 0, time.Local)
+	if err := recorder.Record(Sample{Time: at, Backend: "ddddocr", Text: "a1/b2", Outcome: OutcomeSuccess}, pngHeader); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if err := recorder.Record(Sample{Time: at, Backend: "ddddocr", Text: "", Outcome: OutcomeRejected}, []byte("not an image")); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	// 手工写坏的行应被跳过
+	f, err := os.OpenFile(filepath.Join(dir, samplesFileName), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{broken\n")
+	f.Close()
+
+	samples, err := LoadSamples(dir)
+	if err != nil {
+		t.Fatalf("LoadSamples() error = %v", err)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("len(samples) = %d, want 2", len(samples))
+	}
+
+	tests := []struct {
+		sample Sample
+		image  string
+	}{
+		{samples[0], "images/20250901-093000.000_a1b2_success.png"},
+		{samples[1], "images/20250901-093000.000_empty_rejected.bin"},
+	}
+	for _, tt := range tests {
+		if tt.sample.Image != tt.image {
+			t.Errorf("Image = %q, want %q", tt.sample.Image, tt.image)
+		}
+		if _, err := os.Stat(filepath.Join(dir, tt.sample.Image)); err != nil {
+			t.Errorf("图片文件不存在: %v", err)
+		}
+	}
+	if samples[0].Text != "a1/b2" || samples[0].Backend != "ddddocr" || !samples[0].Time.Equal(at) {
+		t.Errorf("samples[0] = %+v", samples[0])
+	}
+}
+
+func TestLoadSamplesMissingDir(t *testing.T) {
+	if _, err := LoadSamples(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("LoadSamples() error = nil, want error for missing dataset")
+	}
+}
+
+func TestOutcomeJudged(t *testing.T) {
+	tests := []struct {
+		outcome        Outcome
+		correct, known bool
+	}{
+		{OutcomeSuccess, true, true},
+		{OutcomePasswordError, true, true},
+		{OutcomeCaptchaError, false, true},
+		{OutcomeRejected, false, true},
+		{OutcomeUnknown, false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		correct, known := tt.outcome.Judged()
+		if correct != tt.correct || known != tt.known {
+			t.Errorf("%q.Judged() = %v, %v, want %v, %v", tt.outcome, correct, known, tt.correct, tt.known)
+		}
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	day1 := time.Date(2025, 9, 1, 10, 0, 0, 0, time.Local) // 2025-W36
+	day2 := time.Date(2025, 9, 2, 10, 0, 0, 0, time.Local) // 2025-W36
+	day3 := time.Date(2025, 9, 8, 10, 0, 0, 0, time.Local) // 2025-W37
+	samples := []Sample{
+		{Time: day1, Backend: "ddddocr", Outcome: OutcomeSuccess},
+		{Time: day1, Backend: "ddddocr", Outcome: OutcomeCaptchaError},
+		{Time: day2, Backend: "ddddocr", Outcome: OutcomePasswordError},
+		{Time: day3, Backend: "ddddocr", Outcome: OutcomeUnknown},
+		{Time: day1, Backend: "", Outcome: OutcomeRejected},
+	}
+
+	tests := []struct {
+		period string
+		want   []ReportRow
+	}{
+		{"", []ReportRow{
+			{Backend: "ddddocr", Period: "2025-09-01", Total: 2, Correct: 1, Wrong: 1},
+			{Backend: "ddddocr", Period: "2025-09-02", Total: 1, Correct: 1},
+			{Backend: "ddddocr", Period: "2025-09-08", Total: 1, Unknown: 1},
+			{Backend: "unknown", Period: "2025-09-01", Total: 1, Wrong: 1},
+		}},
+		{PeriodWeek, []ReportRow{
+			{Backend: "ddddocr", Period: "2025-W36", Total: 3, Correct: 2, Wrong: 1},
+			{Backend: "ddddocr", Period: "2025-W37", Total: 1, Unknown: 1},
+			{Backend: "unknown", Period: "2025-W36", Total: 1, Wrong: 1},
+		}},
+		{PeriodMonth, []ReportRow{
+			{Backend: "ddddocr", Period: "2025-09", Total: 4, Correct: 2, Wrong: 1, Unknown: 1},
+			{Backend: "unknown", Period: "2025-09", Total: 1, Wrong: 1},
+		}},
+		{PeriodAll, []ReportRow{
+			{Backend: "ddddocr", Period: "all", Total: 4, Correct: 2, Wrong: 1, Unknown: 1},
+			{Backend: "unknown", Period: "all", Total: 1, Wrong: 1},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			rows, err := BuildReport(samples, tt.period)
+			if err != nil {
+				t.Fatalf("BuildReport() error = %v", err)
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("rows = %+v, want %+v", rows, tt.want)
+			}
+			for i := range rows {
+				if rows[i] != tt.want[i] {
+					t.Errorf("rows[%d] = %+v, want %+v", i, rows[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := BuildReport(samples, "year"); err == nil {
+		t.Error("BuildReport(year) error = nil, want unsupported period")
+	}
+}
+
+func TestReportRowAccuracy(t *testing.T) {
+	tests := []struct {
+		row  ReportRow
+		want float64
+	}{
+		{ReportRow{Correct: 3, Wrong: 1, Unknown: 5}, 0.75},
+		{ReportRow{Unknown: 2}, -1},
+	}
+	for _, tt := range tests {
+		if got := tt.row.Accuracy(); got != tt.want {
+			t.Errorf("%+v.Accuracy() = %v, want %v", tt.row, got, tt.want)
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	var sb strings.Builder
+	rows := []ReportRow{
+		{Backend: "ddddocr", Period: "all", Total: 4, Correct: 3, Wrong: 1},
+		{Backend: "http", Period: "all", Total: 1, Unknown: 1},
+	}
+	if err := WriteReport(&sb, rows); err != nil {
+		t.Fatalf("WriteReport() error = %v", err)
+	}
+	out := sb.String()
+	for _, want := range []string{"ACCURACY", "75.0%", "http"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); !strings.HasSuffix(strings.TrimSpace(lines[2]), "-") {
+		t.Errorf("无可判定样本时正确率应为 -：%q", lines[2])
+	}
+}
+
+func TestImageExt(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want string
+	}{
+		{pngHeader, ".png"},
+		{[]byte("\xff\xd8\xff\xe0\x00\x10JFIF"), ".jpg"},
+		{[]byte("GIF89a"), ".gif"},
+		{[]byte("BM"), ".bmp"},
+		{[]byte("plain text"), ".bin"},
+	}
+	for _, tt := range tests {
+		if got := ImageExt(tt.data); got != tt.want {
+			t.Errorf("ImageExt(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
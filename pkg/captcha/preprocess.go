@@ -0,0 +1,396 @@
+package captcha
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	// 注册验证码可能使用的图片解码器
+	_ "image/gif"
+	_ "image/jpeg"
+)
+
+// Filter 是作用于验证码图片的一步预处理。
+type Filter interface {
+	Name() string
+	Apply(img image.Image) image.Image
+}
+
+// Pipeline 按顺序对验证码图片应用一组预处理滤镜。
+// 设置调试目录后，每次处理都会把原图和每一步的中间结果保存为 PNG，便于调参。
+type Pipeline struct {
+	filters  []Filter
+	debugDir string
+}
+
+// NewPipeline 创建预处理流水线，debugDir 为空时不保存中间结果。
+func NewPipeline(filters []Filter, debugDir string) *Pipeline {
+	return &Pipeline{filters: filters, debugDir: debugDir}
+}
+
+// ParseFilters 解析逗号分隔的滤镜描述，例如 "grayscale,median:1,binarize:otsu,delines:1"。
+//
+// 支持的滤镜:
+//
+//	grayscale        转为灰度图
+//	median[:半径]     中值滤波去噪，默认半径 1
+//	binarize[:阈值]   二值化，阈值为 0-255 或 otsu（默认）
+//	delines[:粗细]    去除粗细不超过指定像素的干扰线，默认 1
+//	despeckle[:数量]  去除周围深色像素少于指定数量的孤立噪点，默认 2
+//	scale[:倍数]      最近邻放大，默认 2
+func ParseFilters(spec string) ([]Filter, error) {
+	filters := make([]Filter, 0)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, arg, _ := strings.Cut(item, ":")
+		name = strings.ToLower(strings.TrimSpace(name))
+		arg = strings.ToLower(strings.TrimSpace(arg))
+
+		switch name {
+		case "grayscale":
+			filters = append(filters, grayscaleFilter{})
+		case "median":
+			radius, err := intArg(arg, 1, 1, 5)
+			if err != nil {
+				return nil, fmt.Errorf("滤镜 %s 参数错误: %w", item, err)
+			}
+			filters = append(filters, medianFilter{radius: radius})
+		case "binarize":
+			if arg == "" || arg == "otsu" {
+				filters = append(filters, binarizeFilter{threshold: -1})
+				continue
+			}
+			threshold, err := intArg(arg, 0, 0, 255)
+			if err != nil {
+				return nil, fmt.Errorf("滤镜 %s 参数错误: %w", item, err)
+			}
+			filters = append(filters, binarizeFilter{threshold: threshold})
+		case "delines":
+			width, err := intArg(arg, 1, 1, 5)
+			if err != nil {
+				return nil, fmt.Errorf("滤镜 %s 参数错误: %w", item, err)
+			}
+			filters = append(filters, delinesFilter{width: width})
+		case "despeckle":
+			minNeighbors, err := intArg(arg, 2, 1, 8)
+			if err != nil {
+				return nil, fmt.Errorf("滤镜 %s 参数错误: %w", item, err)
+			}
+			filters = append(filters, despeckleFilter{minNeighbors: minNeighbors})
+		case "scale":
+			factor, err := intArg(arg, 2, 1, 8)
+			if err != nil {
+				return nil, fmt.Errorf("滤镜 %s 参数错误: %w", item, err)
+			}
+			filters = append(filters, scaleFilter{factor: factor})
+		default:
+			return nil, fmt.Errorf("未知的预处理滤镜: %s", name)
+		}
+	}
+	return filters, nil
+}
+
+// Process 解码图片并依次应用滤镜，返回 PNG 编码的结果。
+func (p *Pipeline) Process(data []byte) ([]byte, error) {
+	var debugDir string
+	if p.debugDir != "" {
+		debugDir = filepath.Join(p.debugDir, time.Now().Format("20060102-150405.000"))
+	}
+	return p.process(data, debugDir)
+}
+
+// Dump 与 Process 相同，但总是把原图和每一步的中间结果保存到 dir。
+func (p *Pipeline) Dump(data []byte, dir string) ([]byte, error) {
+	return p.process(data, dir)
+}
+
+func (p *Pipeline) process(data []byte, debugDir string) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解码验证码图片失败: %w", err)
+	}
+
+	if debugDir != "" {
+		if err := os.MkdirAll(debugDir, 0o755); err != nil {
+			return nil, fmt.Errorf("创建预处理调试目录失败: %w", err)
+		}
+		if err := writePNG(filepath.Join(debugDir, "00_original.png"), img); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, filter := range p.filters {
+		img = filter.Apply(img)
+		if debugDir != "" {
+			name := fmt.Sprintf("%02d_%s.png", i+1, filter.Name())
+			if err := writePNG(filepath.Join(debugDir, name), img); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("编码预处理结果失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+type grayscaleFilter struct{}
+
+func (grayscaleFilter) Name() string { return "grayscale" }
+
+func (grayscaleFilter) Apply(img image.Image) image.Image {
+	return toGray(img)
+}
+
+type medianFilter struct {
+	radius int
+}
+
+func (f medianFilter) Name() string { return "median" }
+
+func (f medianFilter) Apply(img image.Image) image.Image {
+	src := toGray(img)
+	bounds := src.Bounds()
+	dst := image.NewGray(bounds)
+	window := make([]uint8, 0, (2*f.radius+1)*(2*f.radius+1))
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			window = window[:0]
+			for dy := -f.radius; dy <= f.radius; dy++ {
+				for dx := -f.radius; dx <= f.radius; dx++ {
+					p := image.Pt(x+dx, y+dy)
+					if p.In(bounds) {
+						window = append(window, src.GrayAt(p.X, p.Y).Y)
+					}
+				}
+			}
+			sort.Slice(window, func(i, j int) bool { return window[i] < window[j] })
+			dst.SetGray(x, y, color.Gray{Y: window[len(window)/2]})
+		}
+	}
+	return dst
+}
+
+// binarizeFilter 二值化，threshold 小于 0 时使用 Otsu 算法自动计算阈值。
+type binarizeFilter struct {
+	threshold int
+}
+
+func (f binarizeFilter) Name() string { return "binarize" }
+
+func (f binarizeFilter) Apply(img image.Image) image.Image {
+	src := toGray(img)
+	threshold := f.threshold
+	if threshold < 0 {
+		threshold = otsuThreshold(src)
+	}
+
+	bounds := src.Bounds()
+	dst := image.NewGray(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if int(src.GrayAt(x, y).Y) <= threshold {
+				dst.SetGray(x, y, color.Gray{Y: 0})
+			} else {
+				dst.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return dst
+}
+
+// delinesFilter 去除干扰线：深色像素在水平或竖直方向上的连续宽度不超过 width 时视为细线并擦除。
+// 验证码字符笔画较粗，干扰线通常只有 1 像素，需在二值化之后使用。
+type delinesFilter struct {
+	width int
+}
+
+func (f delinesFilter) Name() string { return "delines" }
+
+func (f delinesFilter) Apply(img image.Image) image.Image {
+	src := toGray(img)
+	bounds := src.Bounds()
+	dst := image.NewGray(bounds)
+	copy(dst.Pix, src.Pix)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if !isDark(src, x, y) {
+				continue
+			}
+			if darkRun(src, x, y, 1, 0) <= f.width || darkRun(src, x, y, 0, 1) <= f.width {
+				dst.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return dst
+}
+
+// despeckleFilter 去除孤立噪点：8 邻域内深色像素少于 minNeighbors 时擦除。
+type despeckleFilter struct {
+	minNeighbors int
+}
+
+func (f despeckleFilter) Name() string { return "despeckle" }
+
+func (f despeckleFilter) Apply(img image.Image) image.Image {
+	src := toGray(img)
+	bounds := src.Bounds()
+	dst := image.NewGray(bounds)
+	copy(dst.Pix, src.Pix)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if !isDark(src, x, y) {
+				continue
+			}
+			neighbors := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if (dx != 0 || dy != 0) && isDark(src, x+dx, y+dy) {
+						neighbors++
+					}
+				}
+			}
+			if neighbors < f.minNeighbors {
+				dst.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return dst
+}
+
+type scaleFilter struct {
+	factor int
+}
+
+func (f scaleFilter) Name() string { return "scale" }
+
+func (f scaleFilter) Apply(img image.Image) image.Image {
+	src := toGray(img)
+	bounds := src.Bounds()
+	dst := image.NewGray(image.Rect(0, 0, bounds.Dx()*f.factor, bounds.Dy()*f.factor))
+	for y := 0; y < dst.Bounds().Dy(); y++ {
+		for x := 0; x < dst.Bounds().Dx(); x++ {
+			dst.SetGray(x, y, src.GrayAt(bounds.Min.X+x/f.factor, bounds.Min.Y+y/f.factor))
+		}
+	}
+	return dst
+}
+
+func toGray(img image.Image) *image.Gray {
+	if gray, ok := img.(*image.Gray); ok {
+		return gray
+	}
+	bounds := img.Bounds()
+	gray := image.NewGray(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray.Set(x, y, img.At(x, y))
+		}
+	}
+	return gray
+}
+
+// otsuThreshold 使用 Otsu 算法计算使类间方差最大的阈值。
+func otsuThreshold(img *image.Gray) int {
+	var histogram [256]int
+	for _, v := range img.Pix {
+		histogram[v]++
+	}
+
+	total := len(img.Pix)
+	sum := 0.0
+	for i, count := range histogram {
+		sum += float64(i * count)
+	}
+
+	var (
+		sumBackground    float64
+		weightBackground int
+		bestVariance     float64
+		threshold        = 127
+	)
+	for i, count := range histogram {
+		weightBackground += count
+		if weightBackground == 0 {
+			continue
+		}
+		weightForeground := total - weightBackground
+		if weightForeground == 0 {
+			break
+		}
+
+		sumBackground += float64(i * count)
+		meanBackground := sumBackground / float64(weightBackground)
+		meanForeground := (sum - sumBackground) / float64(weightForeground)
+		diff := meanBackground - meanForeground
+		variance := float64(weightBackground) * float64(weightForeground) * diff * diff
+		if variance > bestVariance {
+			bestVariance = variance
+			threshold = i
+		}
+	}
+	return threshold
+}
+
+func isDark(img *image.Gray, x, y int) bool {
+	if !image.Pt(x, y).In(img.Bounds()) {
+		return false
+	}
+	return img.GrayAt(x, y).Y < 128
+}
+
+// darkRun 返回经过 (x, y) 的、沿 (dx, dy) 方向的连续深色像素数量。
+func darkRun(img *image.Gray, x, y, dx, dy int) int {
+	run := 1
+	for i := 1; isDark(img, x+dx*i, y+dy*i); i++ {
+		run++
+	}
+	for i := 1; isDark(img, x-dx*i, y-dy*i); i++ {
+		run++
+	}
+	return run
+}
+
+func intArg(raw string, fallback, lower, upper int) (int, error) {
+	if raw == "" {
+		return fallback, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, err
+	}
+	if v < lower || v > upper {
+		return 0, fmt.Errorf("取值范围为 %d-%d", lower, upper)
+	}
+	return v, nil
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建调试图片失败: %w", err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, img); err != nil {
+		return fmt.Errorf("写入调试图片失败: %w", err)
+	}
+	return nil
+}
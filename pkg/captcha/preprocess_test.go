@@ -0,0 +1,210 @@
+package captcha
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// grayImage 由字符画构造灰度图：'#' 为黑色，'.' 为白色
+func grayImage(rows ...string) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, c := range row {
+			v := uint8(255)
+			if c == '#' {
+				v = 0
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
+
+// asciiRows 将图片按深浅转回字符画，便于比较
+func asciiRows(img image.Image) []string {
+	gray := toGray(img)
+	bounds := gray.Bounds()
+	rows := make([]string, 0, bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		var sb strings.Builder
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if isDark(gray, x, y) {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		rows = append(rows, sb.String())
+	}
+	return rows
+}
+
+func TestParseFilters(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []Filter
+		wantErr bool
+	}{
+		{spec: "", want: []Filter{}},
+		{spec: "grayscale, median ,BINARIZE:OTSU", want: []Filter{grayscaleFilter{}, medianFilter{radius: 1}, binarizeFilter{threshold: -1}}},
+		{spec: "median:3,binarize:100,delines,despeckle:4,scale", want: []Filter{
+			medianFilter{radius: 3}, binarizeFilter{threshold: 100}, delinesFilter{width: 1}, despeckleFilter{minNeighbors: 4}, scaleFilter{factor: 2},
+		}},
+		{spec: "binarize", want: []Filter{binarizeFilter{threshold: -1}}},
+		{spec: "median:0", wantErr: true},
+		{spec: "binarize:256", wantErr: true},
+		{spec: "scale:x", wantErr: true},
+		{spec: "despeckle:9", wantErr: true},
+		{spec: "sharpen", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseFilters(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseFilters(%q) = %v, want error", tt.spec, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFilters(%q) error = %v", tt.spec, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseFilters(%q) = %#v, want %#v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		input  []string
+		want   []string
+	}{
+		{
+			name:   "median removes salt noise",
+			filter: medianFilter{radius: 1},
+			input:  []string{".....", "..#..", ".....", "....."},
+			want:   []string{".....", ".....", ".....", "....."},
+		},
+		{
+			name:   "median keeps solid strokes",
+			filter: medianFilter{radius: 1},
+			input:  []string{"###..", "###..", "###..", "....."},
+			want:   []string{"###..", "###..", "##...", "....."},
+		},
+		{
+			name:   "delines removes thin lines and keeps thick strokes",
+			filter: delinesFilter{width: 1},
+			input: []string{
+				"#######...",
+				"..........",
+				"......###.",
+				"......###.",
+				"......###.",
+			},
+			want: []string{
+				"..........",
+				"..........",
+				"......###.",
+				"......###.",
+				"......###.",
+			},
+		},
+		{
+			name:   "despeckle removes isolated pixels",
+			filter: despeckleFilter{minNeighbors: 2},
+			input:  []string{"#.....", "......", "...##.", "...##."},
+			want:   []string{"......", "......", "...##.", "...##."},
+		},
+		{
+			name:   "scale enlarges with nearest neighbour",
+			filter: scaleFilter{factor: 2},
+			input:  []string{"#.", ".#"},
+			want:   []string{"##..", "##..", "..##", "..##"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := asciiRows(tt.filter.Apply(grayImage(tt.input...)))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%s:\n got %v\nwant %v", tt.filter.Name(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinarizeFilter(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 1))
+	for x, v := range []uint8{20, 90, 160, 230} {
+		img.SetGray(x, 0, color.Gray{Y: v})
+	}
+
+	tests := []struct {
+		threshold int
+		want      []uint8
+	}{
+		{threshold: 100, want: []uint8{0, 0, 255, 255}},
+		{threshold: 160, want: []uint8{0, 0, 0, 255}},
+		{threshold: -1, want: []uint8{0, 0, 255, 255}}, // Otsu 阈值落在两组之间
+	}
+	for _, tt := range tests {
+		got := binarizeFilter{threshold: tt.threshold}.Apply(img).(*image.Gray)
+		if !slices.Equal(got.Pix, tt.want) {
+			t.Errorf("threshold %d: got %v, want %v", tt.threshold, got.Pix, tt.want)
+		}
+	}
+}
+
+func TestPipelineDump(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	src.Set(0, 0, color.RGBA{0x10, 0x10, 0x10, 0xff})
+	src.Set(1, 0, color.White)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	filters, err := ParseFilters("grayscale,scale:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	out, err := NewPipeline(filters, "").Dump(buf.Bytes(), dir)
+	if err != nil {
+		t.Fatalf("Dump() error = %v", err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("结果不是 PNG: %v", err)
+	}
+	if size := img.Bounds().Size(); size != image.Pt(6, 4) {
+		t.Errorf("result size = %v, want (6,4)", size)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{"00_original.png", "01_grayscale.png", "02_scale.png"}; !slices.Equal(names, want) {
+		t.Errorf("debug files = %v, want %v", names, want)
+	}
+
+	if _, err := NewPipeline(filters, filepath.Join(dir, "unused")).Process([]byte("not an image")); err == nil {
+		t.Error("Process() error = nil, want decode error")
+	}
+}
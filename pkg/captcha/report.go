@@ -0,0 +1,118 @@
+package captcha
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// 报告统计周期。
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+	PeriodAll   = "all"
+)
+
+// ReportRow 是某个 OCR 后端在一个统计周期内的识别情况。
+type ReportRow struct {
+	Backend string
+	Period  string
+	Total   int
+	Correct int
+	Wrong   int
+	Unknown int
+}
+
+// Accuracy 返回可判定样本中的识别正确率，无可判定样本时返回 -1。
+func (r ReportRow) Accuracy() float64 {
+	judged := r.Correct + r.Wrong
+	if judged == 0 {
+		return -1
+	}
+	return float64(r.Correct) / float64(judged)
+}
+
+// BuildReport 按 OCR 后端和统计周期汇总样本。
+func BuildReport(samples []Sample, period string) ([]ReportRow, error) {
+	if period == "" {
+		period = PeriodDay
+	}
+	switch period {
+	case PeriodDay, PeriodWeek, PeriodMonth, PeriodAll:
+	default:
+		return nil, fmt.Errorf("不支持的统计周期: %s", period)
+	}
+
+	type rowKey struct {
+		backend string
+		period  string
+	}
+	rows := make(map[rowKey]*ReportRow)
+
+	for _, sample := range samples {
+		key := rowKey{
+			backend: nonEmptyLabel(sample.Backend, "unknown"),
+			period:  periodLabel(sample.Time.Local(), period),
+		}
+		row, ok := rows[key]
+		if !ok {
+			row = &ReportRow{Backend: key.backend, Period: key.period}
+			rows[key] = row
+		}
+
+		row.Total++
+		correct, known := sample.Outcome.Judged()
+		switch {
+		case !known:
+			row.Unknown++
+		case correct:
+			row.Correct++
+		default:
+			row.Wrong++
+		}
+	}
+
+	result := make([]ReportRow, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, *row)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Backend != result[j].Backend {
+			return result[i].Backend < result[j].Backend
+		}
+		return result[i].Period < result[j].Period
+	})
+	return result, nil
+}
+
+// WriteReport 以表格形式输出统计结果。
+func WriteReport(w io.Writer, rows []ReportRow) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BACKEND\tPERIOD\tTOTAL\tCORRECT\tWRONG\tUNKNOWN\tACCURACY")
+	for _, row := range rows {
+		accuracy := "-"
+		if acc := row.Accuracy(); acc >= 0 {
+			accuracy = fmt.Sprintf("%.1f%%", acc*100)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%s\n",
+			row.Backend, row.Period, row.Total, row.Correct, row.Wrong, row.Unknown, accuracy)
+	}
+	return tw.Flush()
+}
+
+func periodLabel(t time.Time, period string) string {
+	switch period {
+	case PeriodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case PeriodMonth:
+		return t.Format("2006-01")
+	case PeriodAll:
+		return "all"
+	default:
+		return t.Format("2006-01-02")
+	}
+}
@@ -0,0 +1,48 @@
+package captcha
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// 强智教务系统 verifycode.servlet 生成的验证码为 4 位，字符仅来自以下集合
+	DefaultAlphabet = "123bcmnvxz"
+	DefaultLength   = 4
+)
+
+// ErrInvalidText 表示识别结果不符合验证码的字符集或长度。
+var ErrInvalidText = errors.New("验证码识别结果不合法")
+
+// Validator 按字符集和长度校验识别结果，避免提交必然错误的验证码。
+type Validator struct {
+	Alphabet string // 允许的字符，为空时不校验字符集
+	Length   int    // 验证码长度，小于等于 0 时不校验长度
+}
+
+// DefaultValidator 返回适用于强智教务系统验证码的校验器。
+func DefaultValidator() Validator {
+	return Validator{Alphabet: DefaultAlphabet, Length: DefaultLength}
+}
+
+// Normalize 去除空白并统一为小写后校验，返回可直接提交的验证码文本。
+func (v Validator) Normalize(text string) (string, error) {
+	text = strings.ToLower(strings.Join(strings.Fields(text), ""))
+	if text == "" {
+		return "", fmt.Errorf("%w: 结果为空", ErrInvalidText)
+	}
+	if v.Length > 0 && utf8.RuneCountInString(text) != v.Length {
+		return "", fmt.Errorf("%w: %q 长度应为 %d", ErrInvalidText, text, v.Length)
+	}
+	if v.Alphabet != "" {
+		alphabet := strings.ToLower(v.Alphabet)
+		for _, r := range text {
+			if !strings.ContainsRune(alphabet, r) {
+				return "", fmt.Errorf("%w: %q 含有字符集外的字符 %q", ErrInvalidText, text, r)
+			}
+		}
+	}
+	return text, nil
+}
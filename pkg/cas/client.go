@@ -1,8 +1,13 @@
 package cas
 
 import (
+	"log"
 	"net/http"
+	"path/filepath"
+	"sync"
 	"time"
+
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/captcha"
 )
 
 const (
@@ -14,10 +19,32 @@ const (
 type Client struct {
 	httpClient *http.Client
 	options    *clientOptions
+	store      *sessionStore
+
+	// loginMu 保证同一时刻只有一个登录流程（保活与会话恢复可能并发触发）
+	loginMu sync.Mutex
+
+	statsMu sync.Mutex
+	stats   *sessionStats
 }
 
 type clientOptions struct {
-	timeout time.Duration
+	timeout             time.Duration
+	captchaRecorder     *captcha.Recorder
+	captchaValidator    captcha.Validator
+	captchaRefreshLimit int
+	preprocessor        CaptchaPreprocessor
+	sessionKey          []byte
+	dataDir             string
+	account             string
+	maxLoginFailures    int
+	loginFailureWindow  time.Duration
+	transport           transportOptions
+}
+
+// CaptchaPreprocessor 在调用 OCR 之前处理验证码图片（去噪、二值化、去干扰线等）
+type CaptchaPreprocessor interface {
+	Process(imageData []byte) ([]byte, error)
 }
 
 // ClientOption 定义配置选项函数类型 (Functional Options Pattern)
@@ -30,28 +57,88 @@ func WithTimeout(d time.Duration) ClientOption {
 	}
 }
 
+// WithCaptchaRecorder 记录每次登录使用的验证码图片、识别结果和登录结果，用于统计识别准确率和积累训练数据
+func WithCaptchaRecorder(r *captcha.Recorder) ClientOption {
+	return func(o *clientOptions) {
+		o.captchaRecorder = r
+	}
+}
+
+// WithCaptchaValidator 设置验证码识别结果的字符集与长度校验规则
+func WithCaptchaValidator(v captcha.Validator) ClientOption {
+	return func(o *clientOptions) {
+		o.captchaValidator = v
+	}
+}
+
+// WithCaptchaRefreshLimit 设置识别结果不合法时重新获取验证码的次数上限
+func WithCaptchaRefreshLimit(n int) ClientOption {
+	return func(o *clientOptions) {
+		if n >= 0 {
+			o.captchaRefreshLimit = n
+		}
+	}
+}
+
+// WithCaptchaPreprocessor 设置验证码预处理器，所有验证码在识别前都会经过它处理
+func WithCaptchaPreprocessor(p CaptchaPreprocessor) ClientOption {
+	return func(o *clientOptions) {
+		o.preprocessor = p
+	}
+}
+
+// WithSessionKey 设置 session 文件的加密密钥（AES-256），未设置时以明文存储
+func WithSessionKey(key []byte) ClientOption {
+	return func(o *clientOptions) {
+		o.sessionKey = key
+	}
+}
+
+// WithDataDir 设置运行时数据目录，默认 data
+func WithDataDir(dir string) ClientOption {
+	return func(o *clientOptions) {
+		o.dataDir = dir
+	}
+}
+
+// WithAccount 按账号隔离 session 文件，存放于 <数据目录>/<账号>/cookies.json
+func WithAccount(username string) ClientOption {
+	return func(o *clientOptions) {
+		o.account = username
+	}
+}
+
 // NewClient 创建一个新的 CAS 客户端
 func NewClient(opts ...ClientOption) (*Client, error) {
 	// 默认配置
 	options := &clientOptions{
-		timeout: DefaultTimeout,
+		timeout:             DefaultTimeout,
+		dataDir:             DefaultDataDir,
+		captchaValidator:    captcha.DefaultValidator(),
+		captchaRefreshLimit: MaxCaptchaRefreshes,
+		maxLoginFailures:    DefaultMaxLoginFailures,
+		loginFailureWindow:  DefaultLoginFailureWindow,
+		transport: transportOptions{
+			userAgent:      DefaultUserAgent,
+			maxIdleConns:   defaultMaxIdleConns,
+			maxIdlePerHost: defaultMaxIdleConnsPerHost,
+		},
 	}
 
 	for _, opt := range opts {
 		opt(options)
 	}
 
-	// 初始化可持久化的 CookieJar
-	jar, err := newPersistentJar()
+	// 初始化 CookieJar，持久化由 sessionStore 负责
+	jar, err := newMemoryJar()
 	if err != nil {
 		return nil, err
 	}
 
 	// 配置 HTTP Transport
-	transport := &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 100,
-		IdleConnTimeout:     90 * time.Second,
+	transport, err := buildTransport(options.transport)
+	if err != nil {
+		return nil, err
 	}
 
 	httpClient := &http.Client{
@@ -60,12 +147,29 @@ func NewClient(opts ...ClientOption) (*Client, error) {
 		Transport: transport,
 	}
 
+	sessionPath := filepath.Join(AccountDir(options.dataDir, options.account), sessionFileName)
+	legacyPath := filepath.Join(options.dataDir, sessionFileName)
+	if migrated, err := MigrateLegacyFile(legacyPath, sessionPath); err != nil {
+		log.Printf("[WARN] 迁移旧版 session 文件失败: %v", err)
+	} else if migrated {
+		log.Printf("[INFO] 已将旧版 session 文件迁移到: %s", sessionPath)
+	}
+
 	return &Client{
 		httpClient: httpClient,
 		options:    options,
+		store: &sessionStore{
+			path: sessionPath,
+			key:  options.sessionKey,
+		},
 	}, nil
 }
 
+// DataDir 返回当前账号的数据目录
+func (c *Client) DataDir() string {
+	return AccountDir(c.options.dataDir, c.options.account)
+}
+
 // GetClient 返回底层的 http.Client，用于已登录后的业务请求
 func (c *Client) GetClient() *http.Client {
 	return c.httpClient
@@ -79,8 +183,8 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 // ResetJar 重置为一个干净的 CookieJar，丢弃所有旧 Cookie。
 // 先清除持久化文件，再创建全新的 jar。
 func (c *Client) ResetJar() {
-	ClearSession()
-	jar, err := newPersistentJar()
+	c.ClearSession()
+	jar, err := newMemoryJar()
 	if err != nil {
 		return
 	}
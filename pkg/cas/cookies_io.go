@@ -0,0 +1,277 @@
+package cas
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/persistent-cookiejar"
+)
+
+const (
+	CookieFormatNetscape = "netscape" // curl / wget 使用的 cookies.txt
+	CookieFormatJSON     = "json"     // Cookie-Editor、EditThisCookie 等浏览器扩展的导入导出格式
+	CookieFormatAuto     = "auto"     // 导入时按内容自动识别
+
+	netscapeHeader   = "# Netscape HTTP Cookie File"
+	netscapeHTTPOnly = "#HttpOnly_"
+)
+
+// browserCookie 浏览器扩展 JSON 格式中的单条 Cookie
+type browserCookie struct {
+	Domain         string  `json:"domain"`
+	ExpirationDate float64 `json:"expirationDate,omitempty"`
+	HostOnly       bool    `json:"hostOnly"`
+	HTTPOnly       bool    `json:"httpOnly"`
+	Name           string  `json:"name"`
+	Path           string  `json:"path"`
+	SameSite       string  `json:"sameSite,omitempty"`
+	Secure         bool    `json:"secure"`
+	Session        bool    `json:"session"`
+	Value          string  `json:"value"`
+}
+
+// ExportCookies 将当前 CookieJar 中的 Cookie 按指定格式写出，返回导出的数量
+func (c *Client) ExportCookies(w io.Writer, format string) (int, error) {
+	jar, ok := c.httpClient.Jar.(*cookiejar.Jar)
+	if !ok {
+		return 0, fmt.Errorf("当前 CookieJar 不支持导出")
+	}
+	cookies := jar.AllCookies()
+
+	switch format {
+	case CookieFormatNetscape:
+		return len(cookies), writeNetscapeCookies(w, cookies)
+	case CookieFormatJSON:
+		return len(cookies), writeBrowserCookies(w, cookies)
+	default:
+		return 0, fmt.Errorf("不支持的 Cookie 格式: %s", format)
+	}
+}
+
+// ImportCookies 读取浏览器导出的 Cookie，替换当前 CookieJar 并保存为 session 文件，
+// 之后 LoginWithCache 即可直接复用该会话而无需识别验证码。返回导入的数量。
+func (c *Client) ImportCookies(r io.Reader, format string) (int, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return 0, fmt.Errorf("读取 Cookie 失败: %w", err)
+	}
+
+	if format == "" || format == CookieFormatAuto {
+		format = detectCookieFormat(content)
+	}
+
+	var stored []storedCookie
+	switch format {
+	case CookieFormatNetscape:
+		stored, err = parseNetscapeCookies(content)
+	case CookieFormatJSON:
+		stored, err = parseBrowserCookies(content)
+	default:
+		return 0, fmt.Errorf("不支持的 Cookie 格式: %s", format)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(stored) == 0 {
+		return 0, fmt.Errorf("没有可导入的 Cookie")
+	}
+
+	jar, err := newMemoryJar()
+	if err != nil {
+		return 0, err
+	}
+	restoreCookies(jar, stored)
+	c.httpClient.Jar = jar
+
+	if err := c.SaveSession(); err != nil {
+		return 0, err
+	}
+	log.Printf("[INFO] 已导入 %d 个 Cookie（%s 格式）", len(stored), format)
+	return len(stored), nil
+}
+
+// exportDomain 以域 Cookie 形式导出（带前导点并对子域名生效）。
+// CookieJar 不保留 HostOnly 信息，按域 Cookie 导出可保证浏览器在教务域名下都能带上。
+func exportDomain(domain string) string {
+	return "." + strings.TrimPrefix(domain, ".")
+}
+
+// isSessionCookie 判断是否为会话级 Cookie（CookieJar 中以极远的过期时间表示）
+func isSessionCookie(ck *http.Cookie) bool {
+	return ck.Expires.IsZero() || time.Until(ck.Expires) > 100*365*24*time.Hour
+}
+
+func writeNetscapeCookies(w io.Writer, cookies []*http.Cookie) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, netscapeHeader)
+	fmt.Fprintln(bw, "# 由 easy-qfnu-xk-monitor 导出，包含登录凭据，请妥善保管")
+	fmt.Fprintln(bw)
+
+	for _, ck := range cookies {
+		domain := exportDomain(ck.Domain)
+		if ck.HttpOnly {
+			domain = netscapeHTTPOnly + domain
+		}
+		var expires int64
+		if !isSessionCookie(ck) {
+			expires = ck.Expires.Unix()
+		}
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, "TRUE", cookiePath(ck.Path), netscapeBool(ck.Secure), expires, ck.Name, ck.Value)
+	}
+	return bw.Flush()
+}
+
+func writeBrowserCookies(w io.Writer, cookies []*http.Cookie) error {
+	list := make([]browserCookie, 0, len(cookies))
+	for _, ck := range cookies {
+		item := browserCookie{
+			Domain:   exportDomain(ck.Domain),
+			HostOnly: false,
+			HTTPOnly: ck.HttpOnly,
+			Name:     ck.Name,
+			Path:     cookiePath(ck.Path),
+			SameSite: "unspecified",
+			Secure:   ck.Secure,
+			Session:  isSessionCookie(ck),
+			Value:    ck.Value,
+		}
+		if !item.Session {
+			item.ExpirationDate = float64(ck.Expires.Unix())
+		}
+		list = append(list, item)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(list)
+}
+
+// detectCookieFormat 以 [ 或 { 开头视为 JSON，否则视为 Netscape 格式
+func detectCookieFormat(content []byte) string {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return CookieFormatJSON
+	}
+	return CookieFormatNetscape
+}
+
+func parseNetscapeCookies(content []byte) ([]storedCookie, error) {
+	var stored []storedCookie
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := false
+		if rest, ok := strings.CutPrefix(line, netscapeHTTPOnly); ok {
+			line = rest
+			httpOnly = true
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			return nil, fmt.Errorf("cookies.txt 第 %d 行格式错误: 需要 7 个以 Tab 分隔的字段", lineNo)
+		}
+		expires, err := strconv.ParseInt(strings.TrimSpace(fields[4]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cookies.txt 第 %d 行过期时间格式错误: %w", lineNo, err)
+		}
+
+		ck := storedCookie{
+			Name:     fields[5],
+			Value:    strings.Join(fields[6:], "\t"),
+			Domain:   fields[0],
+			Path:     cookiePath(fields[2]),
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+		}
+		if expires > 0 {
+			ck.Expires = time.Unix(expires, 0)
+		}
+		stored = appendImported(stored, ck)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取 cookies.txt 失败: %w", err)
+	}
+	return stored, nil
+}
+
+func parseBrowserCookies(content []byte) ([]storedCookie, error) {
+	var list []browserCookie
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		// 单条 Cookie 对象
+		var single browserCookie
+		if err := json.Unmarshal(trimmed, &single); err != nil {
+			return nil, fmt.Errorf("解析 Cookie JSON 失败: %w", err)
+		}
+		list = []browserCookie{single}
+	} else if err := json.Unmarshal(trimmed, &list); err != nil {
+		return nil, fmt.Errorf("解析 Cookie JSON 失败: %w", err)
+	}
+
+	var stored []storedCookie
+	for _, item := range list {
+		ck := storedCookie{
+			Name:     item.Name,
+			Value:    item.Value,
+			Domain:   item.Domain,
+			Path:     cookiePath(item.Path),
+			Secure:   item.Secure,
+			HttpOnly: item.HTTPOnly,
+			HostOnly: item.HostOnly,
+		}
+		if !item.Session && item.ExpirationDate > 0 {
+			sec, frac := math.Modf(item.ExpirationDate)
+			ck.Expires = time.Unix(int64(sec), int64(frac*1e9))
+		}
+		stored = appendImported(stored, ck)
+	}
+	return stored, nil
+}
+
+// appendImported 规范化并追加一条导入的 Cookie，跳过已过期或缺少名称、域名的条目。
+// 会话级 Cookie 补上 sessionCookieTTL 的有效期。
+func appendImported(stored []storedCookie, ck storedCookie) []storedCookie {
+	ck.Domain = strings.TrimPrefix(strings.TrimSpace(ck.Domain), ".")
+	if ck.Name == "" || ck.Domain == "" {
+		return stored
+	}
+
+	now := time.Now()
+	if ck.Expires.IsZero() {
+		ck.Expires = now.Add(sessionCookieTTL)
+	}
+	if !ck.Expires.After(now) {
+		log.Printf("[WARN] 跳过已过期的 Cookie: %s (Domain=%s)", ck.Name, ck.Domain)
+		return stored
+	}
+	return append(stored, ck)
+}
+
+func cookiePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func netscapeBool(v bool) string {
+	if v {
+		return "TRUE"
+	}
+	return "FALSE"
+}
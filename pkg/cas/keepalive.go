@@ -0,0 +1,212 @@
+package cas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	sessionStatsFileName = "session_stats.json"
+
+	// 保留的会话存活时长样本数
+	maxLifetimeSamples = 20
+
+	// DefaultKeepaliveMargin 会话存活时长达到预测寿命的该比例时提前重登
+	DefaultKeepaliveMargin = 0.8
+)
+
+// KeepaliveConfig 会话保活配置
+type KeepaliveConfig struct {
+	Interval time.Duration                   // 探活间隔
+	Margin   float64                         // 提前重登比例，默认 DefaultKeepaliveMargin
+	Relogin  func(ctx context.Context) error // 会话失效或即将过期时调用
+}
+
+// sessionStats 记录会话建立时间与观测到的会话寿命，持久化到账号数据目录
+type sessionStats struct {
+	EstablishedAt time.Time `json:"established_at"`
+	LastValidAt   time.Time `json:"last_valid_at"`
+	Lifetimes     []float64 `json:"lifetimes_seconds"`
+}
+
+// StartKeepalive 在后台定期通过 ValidateSession 探活，
+// 会话失效时记录其实际寿命并重登；会话存活时长接近预测寿命时提前重登。
+// ctx 取消后后台任务退出。
+func (c *Client) StartKeepalive(ctx context.Context, cfg KeepaliveConfig) {
+	if cfg.Interval <= 0 || cfg.Relogin == nil {
+		return
+	}
+	if cfg.Margin <= 0 || cfg.Margin > 1 {
+		cfg.Margin = DefaultKeepaliveMargin
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+
+		log.Printf("[INFO] 会话保活已启动: 间隔=%s", cfg.Interval)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			c.keepaliveTick(ctx, cfg)
+		}
+	}()
+}
+
+func (c *Client) keepaliveTick(ctx context.Context, cfg KeepaliveConfig) {
+	if predicted, ok := c.PredictedLifetime(); ok {
+		age := c.SessionAge()
+		if age >= time.Duration(float64(predicted)*cfg.Margin) {
+			log.Printf("[INFO] 会话已存活 %s，接近预测寿命 %s，提前重登", age.Round(time.Second), predicted.Round(time.Second))
+			if err := cfg.Relogin(ctx); err != nil {
+				log.Printf("[ERROR] 提前重登失败: %v", err)
+			}
+			return
+		}
+	}
+
+	valid, err := c.validateSession(ctx)
+	if err != nil {
+		log.Printf("[WARN] 会话探活请求失败: %v", err)
+		return
+	}
+	if valid {
+		return
+	}
+
+	c.MarkSessionExpired()
+	log.Printf("[WARN] 会话保活检测到会话失效，准备重登")
+	if err := cfg.Relogin(ctx); err != nil {
+		log.Printf("[ERROR] 保活重登失败: %v", err)
+	}
+}
+
+// SessionAge 返回当前会话自建立以来的时长，未知时返回 0
+func (c *Client) SessionAge() time.Duration {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	stats := c.loadStatsLocked()
+	if stats.EstablishedAt.IsZero() {
+		return 0
+	}
+	return time.Since(stats.EstablishedAt)
+}
+
+// PredictedLifetime 根据历史观测的会话寿命（中位数）预测当前会话的寿命
+func (c *Client) PredictedLifetime() (time.Duration, bool) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	stats := c.loadStatsLocked()
+	if len(stats.Lifetimes) == 0 {
+		return 0, false
+	}
+
+	sorted := append([]float64(nil), stats.Lifetimes...)
+	sort.Float64s(sorted)
+	return time.Duration(sorted[len(sorted)/2] * float64(time.Second)), true
+}
+
+// MarkSessionExpired 记录当前会话已失效，并把最后一次确认有效时的存活时长计入寿命样本
+func (c *Client) MarkSessionExpired() {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	stats := c.loadStatsLocked()
+	if stats.EstablishedAt.IsZero() {
+		return
+	}
+
+	if stats.LastValidAt.After(stats.EstablishedAt) {
+		lifetime := stats.LastValidAt.Sub(stats.EstablishedAt)
+		stats.Lifetimes = append(stats.Lifetimes, lifetime.Seconds())
+		if len(stats.Lifetimes) > maxLifetimeSamples {
+			stats.Lifetimes = stats.Lifetimes[len(stats.Lifetimes)-maxLifetimeSamples:]
+		}
+		log.Printf("[INFO] 记录会话寿命: %s", lifetime.Round(time.Second))
+	}
+	stats.EstablishedAt = time.Time{}
+	stats.LastValidAt = time.Time{}
+	c.saveStatsLocked(stats)
+}
+
+// markSessionEstablished 在登录成功后记录会话建立时间
+func (c *Client) markSessionEstablished() {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	stats := c.loadStatsLocked()
+	now := time.Now()
+	stats.EstablishedAt = now
+	stats.LastValidAt = now
+	c.saveStatsLocked(stats)
+}
+
+// markSessionValid 记录会话在当前时刻仍然有效。
+// 每次探活都会调用，LastValidAt 只更新内存，仅在开始跟踪新会话时落盘；
+// 重启后从文件读到的 LastValidAt 偏早，只会让寿命样本偏保守。
+func (c *Client) markSessionValid() {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	stats := c.loadStatsLocked()
+	now := time.Now()
+	stats.LastValidAt = now
+	if stats.EstablishedAt.IsZero() {
+		// 复用的旧会话建立时间未知，以首次确认有效的时间作为保守估计
+		stats.EstablishedAt = now
+		c.saveStatsLocked(stats)
+	}
+}
+
+func (c *Client) statsPath() string {
+	return filepath.Join(c.DataDir(), sessionStatsFileName)
+}
+
+func (c *Client) loadStatsLocked() *sessionStats {
+	if c.stats != nil {
+		return c.stats
+	}
+
+	c.stats = &sessionStats{}
+	content, err := os.ReadFile(c.statsPath())
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[WARN] 读取会话统计失败: %v", err)
+		}
+		return c.stats
+	}
+	if err := json.Unmarshal(content, c.stats); err != nil {
+		log.Printf("[WARN] 解析会话统计失败: %v", err)
+		c.stats = &sessionStats{}
+	}
+	return c.stats
+}
+
+func (c *Client) saveStatsLocked(stats *sessionStats) {
+	if err := c.writeStats(stats); err != nil {
+		log.Printf("[WARN] 保存会话统计失败: %v", err)
+	}
+}
+
+func (c *Client) writeStats(stats *sessionStats) error {
+	content, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化会话统计失败: %w", err)
+	}
+	if err := os.MkdirAll(c.DataDir(), 0o700); err != nil {
+		return fmt.Errorf("创建数据目录失败: %w", err)
+	}
+	return writePrivateFile(c.statsPath(), content)
+}
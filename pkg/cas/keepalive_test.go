@@ -0,0 +1,63 @@
+package cas
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSessionStatsPersistence(t *testing.T) {
+	client, err := NewClient(WithDataDir(t.TempDir()), WithAccount("2021001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	readStats := func() sessionStats {
+		t.Helper()
+		var stats sessionStats
+		content, err := os.ReadFile(client.statsPath())
+		if err != nil {
+			t.Fatalf("读取会话统计失败: %v", err)
+		}
+		if err := json.Unmarshal(content, &stats); err != nil {
+			t.Fatal(err)
+		}
+		return stats
+	}
+
+	// 复用的旧会话首次确认有效时落盘建立时间
+	client.markSessionValid()
+	first := readStats()
+	if first.EstablishedAt.IsZero() {
+		t.Fatal("EstablishedAt 未落盘")
+	}
+
+	// 之后的探活只更新内存
+	time.Sleep(10 * time.Millisecond)
+	client.markSessionValid()
+	if got := readStats(); !got.LastValidAt.Equal(first.LastValidAt) {
+		t.Errorf("探活成功后会话统计被重写: LastValidAt %v -> %v", first.LastValidAt, got.LastValidAt)
+	}
+	if !client.stats.LastValidAt.After(first.LastValidAt) {
+		t.Errorf("内存中的 LastValidAt 未更新")
+	}
+
+	// 会话失效时按内存中的 LastValidAt 记录寿命并落盘
+	client.MarkSessionExpired()
+	expired := readStats()
+	if len(expired.Lifetimes) != 1 || !expired.EstablishedAt.IsZero() {
+		t.Fatalf("失效后会话统计 = %+v, want 1 个寿命样本", expired)
+	}
+	if want := client.stats.Lifetimes[0]; expired.Lifetimes[0] != want || want < 0.01 {
+		t.Errorf("寿命样本 = %v, want >= 0.01s", expired.Lifetimes[0])
+	}
+	if predicted, ok := client.PredictedLifetime(); !ok || predicted <= 0 {
+		t.Errorf("PredictedLifetime() = %v, %v", predicted, ok)
+	}
+
+	// 新登录的会话立即落盘
+	client.markSessionEstablished()
+	if got := readStats(); got.EstablishedAt.IsZero() || len(got.Lifetimes) != 1 {
+		t.Errorf("登录后会话统计 = %+v", got)
+	}
+}
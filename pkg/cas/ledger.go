@@ -0,0 +1,201 @@
+package cas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	loginLedgerFileName = "login_ledger.json"
+
+	// DefaultMaxLoginFailures 时间窗口内允许的登录失败次数
+	DefaultMaxLoginFailures = 10
+
+	// DefaultLoginFailureWindow 登录失败次数的统计窗口
+	DefaultLoginFailureWindow = 30 * time.Minute
+)
+
+var (
+	// ErrCredentialsRejected 表示当前账号密码曾被教务系统判定为错误，修改配置前不再尝试登录。
+	ErrCredentialsRejected = errors.New("账号或密码曾被教务系统拒绝，请修改配置后再试")
+
+	// ErrTooManyLoginFailures 表示时间窗口内的登录失败次数已达上限。
+	ErrTooManyLoginFailures = errors.New("登录失败次数过多，暂停登录以免账号被锁定")
+)
+
+// LoginBlockedError 登录被失败台账拦截，未向教务系统发出任何请求
+type LoginBlockedError struct {
+	Err   error     // ErrCredentialsRejected 或 ErrTooManyLoginFailures
+	Until time.Time // 可再次尝试的时间，零值表示需修改配置
+	Since time.Time // 触发拦截的失败时间
+}
+
+func (e *LoginBlockedError) Error() string {
+	if e.Until.IsZero() {
+		return fmt.Sprintf("%v（记录于 %s）", e.Err, e.Since.Format("2006-01-02 15:04:05"))
+	}
+	return fmt.Sprintf("%v，%s 后可再次尝试", e.Err, e.Until.Format("2006-01-02 15:04:05"))
+}
+
+func (e *LoginBlockedError) Unwrap() error {
+	return e.Err
+}
+
+// IsLoginBlocked 判断登录是否被失败台账拦截
+func IsLoginBlocked(err error) bool {
+	return errors.Is(err, ErrCredentialsRejected) || errors.Is(err, ErrTooManyLoginFailures)
+}
+
+// loginLedger 跨进程持久化的登录失败台账，存放于账号数据目录。
+// 仅记录已提交到教务系统的登录尝试，获取或识别验证码失败不计入。
+type loginLedger struct {
+	Failures []loginFailure      `json:"failures"`
+	Rejected *rejectedCredential `json:"rejected,omitempty"`
+}
+
+type loginFailure struct {
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason"`
+}
+
+// rejectedCredential 记录被判定为密码错误的凭据指纹（不保存明文）
+type rejectedCredential struct {
+	Fingerprint string    `json:"fingerprint"`
+	At          time.Time `json:"at"`
+	Message     string    `json:"message"`
+}
+
+// WithLoginLimit 设置时间窗口内允许的登录失败次数，达到上限后在窗口滑出前拒绝登录
+func WithLoginLimit(maxFailures int, window time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		if maxFailures > 0 {
+			o.maxLoginFailures = maxFailures
+		}
+		if window > 0 {
+			o.loginFailureWindow = window
+		}
+	}
+}
+
+// credentialFingerprint 计算账号密码的指纹，用于判断配置是否已修改
+func credentialFingerprint(username, password string) string {
+	sum := sha256.Sum256([]byte("qxk-login:" + username + "\x00" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkLoginAllowed 检查台账，凭据曾被拒绝或失败次数已达上限时返回 *LoginBlockedError
+func (c *Client) checkLoginAllowed(username, password string) error {
+	ledger := c.loadLedger()
+	changed := false
+
+	if rejected := ledger.Rejected; rejected != nil {
+		if rejected.Fingerprint == credentialFingerprint(username, password) {
+			return &LoginBlockedError{Err: ErrCredentialsRejected, Since: rejected.At}
+		}
+		log.Printf("[INFO] 检测到账号或密码已修改，解除密码错误封锁")
+		ledger.Rejected = nil
+		ledger.Failures = nil
+		changed = true
+	}
+
+	if ledger.pruneFailures(c.options.loginFailureWindow) {
+		changed = true
+	}
+	if changed {
+		c.saveLedger(ledger)
+	}
+
+	if len(ledger.Failures) >= c.options.maxLoginFailures {
+		oldest := ledger.Failures[len(ledger.Failures)-c.options.maxLoginFailures]
+		return &LoginBlockedError{
+			Err:   ErrTooManyLoginFailures,
+			Since: oldest.At,
+			Until: oldest.At.Add(c.options.loginFailureWindow),
+		}
+	}
+	return nil
+}
+
+// recordLoginFailure 记录一次已提交的失败登录，密码错误或需要修改密码时封锁当前凭据
+func (c *Client) recordLoginFailure(username, password string, loginErr error) {
+	ledger := c.loadLedger()
+	now := time.Now()
+	ledger.Failures = append(ledger.Failures, loginFailure{At: now, Reason: loginErr.Error()})
+	ledger.pruneFailures(c.options.loginFailureWindow)
+
+	var typed *LoginError
+	if errors.As(loginErr, &typed) && (typed.Type == LoginErrorPassword || typed.Type == LoginErrorPasswordExpired) {
+		ledger.Rejected = &rejectedCredential{
+			Fingerprint: credentialFingerprint(username, password),
+			At:          now,
+			Message:     typed.Message,
+		}
+		log.Printf("[ERROR] 教务系统提示%s，修改配置前将不再尝试登录", typed.Message)
+	}
+	c.saveLedger(ledger)
+}
+
+// recordLoginSuccess 登录成功后清空失败记录
+func (c *Client) recordLoginSuccess() {
+	ledger := c.loadLedger()
+	if len(ledger.Failures) == 0 && ledger.Rejected == nil {
+		return
+	}
+	c.saveLedger(&loginLedger{})
+}
+
+// pruneFailures 移除统计窗口之外的失败记录，返回是否有变化
+func (l *loginLedger) pruneFailures(window time.Duration) bool {
+	cutoff := time.Now().Add(-window)
+	kept := l.Failures[:0]
+	for _, f := range l.Failures {
+		if f.At.After(cutoff) {
+			kept = append(kept, f)
+		}
+	}
+	changed := len(kept) != len(l.Failures)
+	l.Failures = kept
+	return changed
+}
+
+func (c *Client) ledgerPath() string {
+	return filepath.Join(c.DataDir(), loginLedgerFileName)
+}
+
+func (c *Client) loadLedger() *loginLedger {
+	ledger := &loginLedger{}
+	content, err := os.ReadFile(c.ledgerPath())
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[WARN] 读取登录台账失败: %v", err)
+		}
+		return ledger
+	}
+	if err := json.Unmarshal(content, ledger); err != nil {
+		log.Printf("[WARN] 解析登录台账失败: %v", err)
+		return &loginLedger{}
+	}
+	return ledger
+}
+
+func (c *Client) saveLedger(ledger *loginLedger) {
+	content, err := json.MarshalIndent(ledger, "", "  ")
+	if err != nil {
+		log.Printf("[WARN] 序列化登录台账失败: %v", err)
+		return
+	}
+	if err := os.MkdirAll(c.DataDir(), 0o700); err != nil {
+		log.Printf("[WARN] 创建数据目录失败: %v", err)
+		return
+	}
+	if err := writePrivateFile(c.ledgerPath(), content); err != nil {
+		log.Printf("[WARN] 保存登录台账失败: %v", err)
+	}
+}
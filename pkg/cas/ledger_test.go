@@ -0,0 +1,124 @@
+package cas
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCredentialFingerprint(t *testing.T) {
+	base := credentialFingerprint("2021001", "secret")
+	tests := []struct {
+		username, password string
+		same               bool
+	}{
+		{"2021001", "secret", true},
+		{"2021001", "Secret", false},
+		{"2021002", "secret", false},
+		// 用户名与密码之间有分隔符，拼接结果相同也不应冲突
+		{"2021001s", "ecret", false},
+	}
+	for _, tt := range tests {
+		if got := credentialFingerprint(tt.username, tt.password); (got == base) != tt.same {
+			t.Errorf("credentialFingerprint(%q, %q) 与基准相同 = %v, want %v", tt.username, tt.password, got == base, tt.same)
+		}
+	}
+	if len(base) != 64 || base == "secret" {
+		t.Errorf("fingerprint = %q, want hex sha256", base)
+	}
+}
+
+func TestPruneFailures(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		ages        []time.Duration
+		wantKept    int
+		wantChanged bool
+	}{
+		{"empty", nil, 0, false},
+		{"all inside window", []time.Duration{time.Minute, 29 * time.Minute}, 2, false},
+		{"drops expired", []time.Duration{31 * time.Minute, time.Hour, time.Minute}, 1, true},
+		{"all expired", []time.Duration{31 * time.Minute}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := &loginLedger{}
+			for _, age := range tt.ages {
+				ledger.Failures = append(ledger.Failures, loginFailure{At: now.Add(-age)})
+			}
+			if changed := ledger.pruneFailures(30 * time.Minute); changed != tt.wantChanged {
+				t.Errorf("pruneFailures() = %v, want %v", changed, tt.wantChanged)
+			}
+			if len(ledger.Failures) != tt.wantKept {
+				t.Errorf("kept %d failures, want %d", len(ledger.Failures), tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestLoginLedger(t *testing.T) {
+	captchaErr := &LoginError{Type: LoginErrorCaptcha, Message: "验证码错误"}
+	passwordErr := &LoginError{Type: LoginErrorPassword, Message: "用户名或密码错误"}
+	expiredErr := &LoginError{Type: LoginErrorPasswordExpired, Message: "密码已过期"}
+
+	tests := []struct {
+		name     string
+		failures []error
+		password string // 检查时使用的密码
+		want     error
+	}{
+		{name: "no failures", password: "secret"},
+		{name: "below limit", failures: []error{captchaErr, captchaErr}, password: "secret"},
+		{name: "limit reached", failures: []error{captchaErr, captchaErr, captchaErr}, password: "secret", want: ErrTooManyLoginFailures},
+		{name: "wrong password blocks", failures: []error{passwordErr}, password: "secret", want: ErrCredentialsRejected},
+		{name: "expired password blocks", failures: []error{expiredErr}, password: "secret", want: ErrCredentialsRejected},
+		{name: "changed password unblocks", failures: []error{passwordErr, captchaErr, captchaErr}, password: "new-secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(WithDataDir(t.TempDir()), WithAccount("2021001"), WithLoginLimit(3, time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, failure := range tt.failures {
+				client.recordLoginFailure("2021001", "secret", failure)
+			}
+
+			err = client.checkLoginAllowed("2021001", tt.password)
+			if !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+				t.Fatalf("checkLoginAllowed() error = %v, want %v", err, tt.want)
+			}
+			if IsLoginBlocked(err) != (tt.want != nil) {
+				t.Errorf("IsLoginBlocked(%v) = %v", err, IsLoginBlocked(err))
+			}
+
+			var blocked *LoginBlockedError
+			if errors.As(err, &blocked) {
+				// 密码错误需修改配置，次数超限在最早一次失败滑出窗口后解除
+				if wantUntil := tt.want == ErrTooManyLoginFailures; blocked.Until.IsZero() == wantUntil {
+					t.Errorf("Until = %v, want set = %v", blocked.Until, wantUntil)
+				}
+			}
+		})
+	}
+}
+
+func TestLoginLedgerSuccessResets(t *testing.T) {
+	client, err := NewClient(WithDataDir(t.TempDir()), WithAccount("2021001"), WithLoginLimit(1, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.recordLoginFailure("2021001", "secret", errors.New("网络错误"))
+	if err := client.checkLoginAllowed("2021001", "secret"); !errors.Is(err, ErrTooManyLoginFailures) {
+		t.Fatalf("checkLoginAllowed() error = %v, want ErrTooManyLoginFailures", err)
+	}
+
+	client.recordLoginSuccess()
+	if err := client.checkLoginAllowed("2021001", "secret"); err != nil {
+		t.Errorf("登录成功后 checkLoginAllowed() error = %v, want nil", err)
+	}
+	if ledger := client.loadLedger(); len(ledger.Failures) != 0 || ledger.Rejected != nil {
+		t.Errorf("登录成功后台账 = %+v, want empty", ledger)
+	}
+}
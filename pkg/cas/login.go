@@ -1,10 +1,8 @@
 package cas
 
 import (
-	"bytes"
 	"context"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -13,6 +11,8 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/captcha"
 )
 
 const (
@@ -26,127 +26,45 @@ const (
 
 	// 验证码最大重试次数
 	MaxCaptchaRetries = 3
-)
-
-// OCRClient 定义验证码识别客户端接口
-type OCRClient interface {
-	Recognize(imageData []byte) (string, error)
-}
-
-// DefaultOCRClient 默认的 OCR 客户端，调用外部 ddddocr API
-type DefaultOCRClient struct {
-	apiURL string
-}
-
-// NewDefaultOCRClient 创建默认 OCR 客户端
-func NewDefaultOCRClient(apiURL string) *DefaultOCRClient {
-	return &DefaultOCRClient{apiURL: apiURL}
-}
-
-// Recognize 调用 OCR API 识别验证码
-func (c *DefaultOCRClient) Recognize(imageData []byte) (string, error) {
-	// 将图片转为 base64
-	imageBase64 := base64.StdEncoding.EncodeToString(imageData)
-
-	// 构建请求体
-	reqBody := map[string]string{
-		"image": imageBase64,
-	}
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("构建请求体失败: %w", err)
-	}
-
-	// 发送请求到 OCR API
-	req, err := http.NewRequest("POST", c.apiURL+"/ocr/base64", bytes.NewReader(jsonBody))
-	if err != nil {
-		return "", fmt.Errorf("创建 OCR 请求失败: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("OCR API 请求失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("OCR API 返回错误状态码: %d", resp.StatusCode)
-	}
-
-	// 解析响应
-	var result struct {
-		Success bool `json:"success"`
-		Data    struct {
-			Text string `json:"text"`
-		} `json:"data"`
-		Message string `json:"message"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("解析 OCR 响应失败: %w", err)
-	}
 
-	if !result.Success {
-		return "", fmt.Errorf("OCR 识别失败: %s", result.Message)
-	}
-
-	return result.Data.Text, nil
-}
-
-// LoginError 登录错误类型
-type LoginError struct {
-	Type    string // "captcha" 或 "password"
-	Message string
-}
-
-func (e *LoginError) Error() string {
-	return e.Message
-}
-
-// IsCaptchaError 判断是否为验证码错误
-func IsCaptchaError(err error) bool {
-	var loginErr *LoginError
-	if errors.As(err, &loginErr) {
-		return loginErr.Type == "captcha"
-	}
-	return false
-}
-
-// IsPasswordError 判断是否为密码错误
-func IsPasswordError(err error) bool {
-	var loginErr *LoginError
-	if errors.As(err, &loginErr) {
-		return loginErr.Type == "password"
-	}
-	return false
-}
+	// 单次登录尝试中，识别结果不合法时最多重新获取验证码的次数（不提交，不计入 MaxCaptchaRetries）
+	MaxCaptchaRefreshes = 5
+)
 
 // ValidateSession 验证当前 Cookie 是否仍然有效
 // 通过访问教务系统主页并检测响应页面是否包含 SuccessMark 来判断
 func (c *Client) ValidateSession(ctx context.Context) bool {
+	valid, err := c.validateSession(ctx)
+	return err == nil && valid
+}
+
+// validateSession 与 ValidateSession 相同，但区分网络错误与会话失效
+func (c *Client) validateSession(ctx context.Context) (bool, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", MainPageURL, nil)
 	if err != nil {
-		return false
+		return false, err
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return false
+		return false, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return false
+		return false, nil
 	}
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return false
+		return false, err
 	}
 
-	return strings.Contains(string(bodyBytes), SuccessMark)
+	if !strings.Contains(string(bodyBytes), SuccessMark) {
+		return false, nil
+	}
+	c.markSessionValid()
+	return true, nil
 }
 
 // LoginWithCache 优先使用缓存的 session 登录，失败后回退到完整登录流程
@@ -159,6 +77,7 @@ func (c *Client) LoginWithCache(ctx context.Context, username, password string,
 			return nil
 		}
 		log.Println("[INFO] 缓存会话已失效，执行完整登录流程")
+		c.MarkSessionExpired()
 		// 重置为干净的 jar，避免旧 Cookie 干扰新的登录流程
 		c.ResetJar()
 	}
@@ -178,6 +97,23 @@ func (c *Client) LoginWithCache(ctx context.Context, username, password string,
 
 // Login 执行强智教务系统的登录流程
 func (c *Client) Login(ctx context.Context, username, password string, ocrClient OCRClient) error {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+
+	if err := c.login(ctx, username, password, ocrClient); err != nil {
+		return err
+	}
+	c.recordLoginSuccess()
+	c.markSessionEstablished()
+	return nil
+}
+
+func (c *Client) login(ctx context.Context, username, password string, ocrClient OCRClient) error {
+	// 0. 检查失败台账，被拦截时不向教务系统发出任何请求
+	if err := c.checkLoginAllowed(username, password); err != nil {
+		return err
+	}
+
 	// 1. 访问首页获取初始 Cookie
 	if err := c.visitIndex(ctx); err != nil {
 		return err
@@ -186,6 +122,13 @@ func (c *Client) Login(ctx context.Context, username, password string, ocrClient
 	// 2. 登录循环（最多重试 MaxCaptchaRetries 次）
 	var lastErr error
 	for attempt := 1; attempt <= MaxCaptchaRetries; attempt++ {
+		// 重试前再次检查失败台账，避免连续失败导致账号被锁定
+		if attempt > 1 {
+			if err := c.checkLoginAllowed(username, password); err != nil {
+				return err
+			}
+		}
+
 		log.Printf("[INFO] 登录尝试 %d/%d", attempt, MaxCaptchaRetries)
 
 		err := c.loginAttempt(ctx, username, password, ocrClient)
@@ -196,12 +139,7 @@ func (c *Client) Login(ctx context.Context, username, password string, ocrClient
 
 		lastErr = err
 
-		// 如果是密码错误，直接返回，不需要重试
-		if IsPasswordError(err) {
-			return err
-		}
-
-		// 如果是验证码错误，继续重试
+		// 如果是验证码错误，继续重试；密码错误、账号锁定等其他错误直接返回，由调用方决定后续处理
 		if IsCaptchaError(err) {
 			log.Printf("[WARN] 验证码错误，准备重试")
 			// 短暂延迟后重试
@@ -242,19 +180,12 @@ func (c *Client) visitIndex(ctx context.Context) error {
 
 // loginAttempt 执行一次登录尝试
 func (c *Client) loginAttempt(ctx context.Context, username, password string, ocrClient OCRClient) error {
-	// 1. 获取验证码图片
-	captchaData, err := c.getCaptcha(ctx)
+	// 1-2. 获取并识别验证码，直到识别结果通过校验
+	captchaData, captchaText, err := c.recognizeCaptcha(ctx, ocrClient)
 	if err != nil {
 		return err
 	}
 
-	// 2. 识别验证码
-	captchaText, err := ocrClient.Recognize(captchaData)
-	if err != nil {
-		return fmt.Errorf("验证码识别失败: %w", err)
-	}
-	log.Printf("[DEBUG] 验证码识别结果: %s", captchaText)
-
 	// 3. 生成 encoded 字符串
 	encoded := generateEncoded(username, password)
 
@@ -265,7 +196,98 @@ func (c *Client) loginAttempt(ctx context.Context, username, password string, oc
 	}
 
 	// 5. 判断登录结果
-	return c.checkLoginResult(respBody)
+	err = c.checkLoginResult(respBody)
+	c.recordCaptcha(captchaData, captchaText, ocrClient, err)
+	if err != nil {
+		c.recordLoginFailure(username, password, err)
+	}
+	return err
+}
+
+// recognizeCaptcha 获取并识别验证码。
+// 识别结果不符合验证码字符集或长度时直接换一张验证码，不提交登录，
+// 最多重新获取 captchaRefreshLimit 次，避免无谓消耗登录次数。
+func (c *Client) recognizeCaptcha(ctx context.Context, ocrClient OCRClient) ([]byte, string, error) {
+	var lastErr error
+	for refresh := 0; refresh <= c.options.captchaRefreshLimit; refresh++ {
+		if refresh > 0 {
+			log.Printf("[WARN] %v，重新获取验证码 %d/%d", lastErr, refresh, c.options.captchaRefreshLimit)
+		}
+
+		captchaData, err := c.getCaptcha(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+
+		rawText, err := ocrClient.Recognize(ctx, c.preprocessCaptcha(captchaData))
+		if err != nil {
+			return nil, "", fmt.Errorf("验证码识别失败: %w", err)
+		}
+		log.Printf("[DEBUG] 验证码识别结果: %s", rawText)
+
+		captchaText, err := c.options.captchaValidator.Normalize(rawText)
+		if err == nil {
+			return captchaData, captchaText, nil
+		}
+
+		lastErr = err
+		c.recordCaptcha(captchaData, rawText, ocrClient, err)
+	}
+
+	return nil, "", fmt.Errorf("连续 %d 张验证码识别结果均不合法: %w", c.options.captchaRefreshLimit+1, lastErr)
+}
+
+// preprocessCaptcha 对验证码图片做识别前预处理，失败时回退为原图
+func (c *Client) preprocessCaptcha(captchaData []byte) []byte {
+	if c.options.preprocessor == nil {
+		return captchaData
+	}
+
+	processed, err := c.options.preprocessor.Process(captchaData)
+	if err != nil {
+		log.Printf("[WARN] 验证码预处理失败，使用原图识别: %v", err)
+		return captchaData
+	}
+	return processed
+}
+
+// recordCaptcha 在启用数据集记录时保存本次验证码样本
+func (c *Client) recordCaptcha(image []byte, text string, ocrClient OCRClient, loginErr error) {
+	recorder := c.options.captchaRecorder
+	if recorder == nil {
+		return
+	}
+
+	sample := captcha.Sample{
+		Backend: ocrBackendName(ocrClient),
+		Text:    text,
+		Outcome: captcha.OutcomeSuccess,
+	}
+	switch {
+	case loginErr == nil:
+	case errors.Is(loginErr, captcha.ErrInvalidText):
+		sample.Outcome = captcha.OutcomeRejected
+		sample.Detail = loginErr.Error()
+	case IsCaptchaError(loginErr):
+		sample.Outcome = captcha.OutcomeCaptchaError
+	case IsPasswordError(loginErr):
+		sample.Outcome = captcha.OutcomePasswordError
+	default:
+		sample.Outcome = captcha.OutcomeUnknown
+		sample.Detail = loginErr.Error()
+	}
+
+	if err := recorder.Record(sample, image); err != nil {
+		log.Printf("[WARN] 保存验证码样本失败: %v", err)
+	}
+}
+
+// ocrBackendName 返回 OCR 客户端的名称，未实现 Name 方法时使用类型名
+func ocrBackendName(ocrClient OCRClient) string {
+	if named, ok := ocrClient.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return fmt.Sprintf("%T", ocrClient)
 }
 
 // getCaptcha 获取验证码图片
@@ -339,15 +361,10 @@ func (c *Client) submitLogin(ctx context.Context, captchaText, encoded string) (
 	return string(bodyBytes), nil
 }
 
-// checkLoginResult 检查登录结果
+// checkLoginResult 检查登录结果。
+// 先以主页的登录成功标识为准，只有未检测到成功标识时才按页面提示识别失败原因，
+// 避免已登录页面上的公告（如“开放时间”“系统维护”）被误判为登录失败。
 func (c *Client) checkLoginResult(respBody string) error {
-	if strings.Contains(respBody, "验证码错误") {
-		return &LoginError{Type: "captcha", Message: "验证码错误"}
-	}
-	if strings.Contains(respBody, "密码错误") {
-		return &LoginError{Type: "password", Message: "用户名或密码错误"}
-	}
-
 	// 验证登录状态
 	req, err := http.NewRequestWithContext(context.Background(), "GET", MainPageURL, nil)
 	if err != nil {
@@ -356,22 +373,38 @@ func (c *Client) checkLoginResult(respBody string) error {
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		if loginErr := classifyLoginResult(respBody); loginErr != nil {
+			return loginErr
+		}
 		return fmt.Errorf("验证登录状态失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	bodyBytes, _ := io.ReadAll(resp.Body)
-	if !strings.Contains(string(bodyBytes), SuccessMark) {
-		return errors.New("登录流程结束，但未检测到登录成功标识")
+	if strings.Contains(string(bodyBytes), SuccessMark) {
+		log.Println("[INFO] 检测到登录成功标识，登录流程完成")
+		return nil
 	}
 
-	log.Println("[INFO] 检测到登录成功标识，登录流程完成")
-	return nil
+	for _, body := range []string{respBody, string(bodyBytes)} {
+		if loginErr := classifyLoginResult(body); loginErr != nil {
+			return loginErr
+		}
+	}
+	return unknownLoginError("登录流程结束，但未检测到登录成功标识", respBody)
+}
+
+// UserAgent 返回请求使用的 User-Agent
+func (c *Client) UserAgent() string {
+	if c.options.transport.userAgent == "" {
+		return DefaultUserAgent
+	}
+	return c.options.transport.userAgent
 }
 
 // setBrowserHeaders 设置浏览器请求头
 func (c *Client) setBrowserHeaders(req *http.Request) {
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("User-Agent", c.UserAgent())
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
 	req.Header.Set("Connection", "keep-alive")
@@ -0,0 +1,251 @@
+package cas
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/captcha"
+)
+
+const (
+	// OCR 图片上传方式
+	OCRModeBase64 = "base64" // JSON 请求体携带 base64 图片
+	OCRModeFile   = "file"   // multipart/form-data 上传图片文件
+
+	DefaultOCRTimeout = 10 * time.Second
+)
+
+// OCRClient 定义验证码识别客户端接口
+type OCRClient interface {
+	Recognize(ctx context.Context, imageData []byte) (string, error)
+}
+
+// OCRConfig HTTP OCR 适配器配置
+// 字段路径使用点号分隔，例如 "data.text"、"result.0.words"
+type OCRConfig struct {
+	APIURL       string        // 服务地址，例如 http://127.0.0.1:5000
+	Mode         string        // 上传方式，默认 base64
+	Endpoint     string        // 接口路径，默认按上传方式取 /ocr/base64 或 /ocr/file
+	ImageField   string        // base64 模式为请求体字段路径（默认 image），file 模式为表单字段名（默认 file）
+	TextField    string        // 响应中识别文本的字段路径，默认 data.text
+	SuccessField string        // 响应中成功标识的字段路径，默认 success，设为 "-" 时不校验
+	SuccessValue string        // 成功标识的期望值，为空时要求布尔 true
+	MessageField string        // 响应中错误信息的字段路径，默认 message
+	Timeout      time.Duration // 单次识别超时，默认 10s
+	Name         string        // 后端名称，用于数据集统计，默认为接口完整地址
+}
+
+// HTTPOCRClient 通过 HTTP 调用自建 OCR 服务（默认兼容 ddddocr API）
+type HTTPOCRClient struct {
+	cfg    OCRConfig
+	client *http.Client
+}
+
+// NewDefaultOCRClient 创建默认 OCR 客户端，调用 ddddocr API 的 /ocr/base64 接口
+func NewDefaultOCRClient(apiURL string) *HTTPOCRClient {
+	return NewHTTPOCRClient(OCRConfig{APIURL: apiURL})
+}
+
+// NewHTTPOCRClient 按配置创建 HTTP OCR 客户端，未填写的字段使用 ddddocr API 的默认值
+func NewHTTPOCRClient(cfg OCRConfig) *HTTPOCRClient {
+	cfg.APIURL = strings.TrimRight(strings.TrimSpace(cfg.APIURL), "/")
+	if cfg.Mode == "" {
+		cfg.Mode = OCRModeBase64
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "/ocr/" + cfg.Mode
+	}
+	if cfg.ImageField == "" {
+		cfg.ImageField = "image"
+		if cfg.Mode == OCRModeFile {
+			cfg.ImageField = "file"
+		}
+	}
+	if cfg.TextField == "" {
+		cfg.TextField = "data.text"
+	}
+	if cfg.SuccessField == "" {
+		cfg.SuccessField = "success"
+	}
+	if cfg.MessageField == "" {
+		cfg.MessageField = "message"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultOCRTimeout
+	}
+	if cfg.Name == "" {
+		cfg.Name = cfg.APIURL + cfg.Endpoint
+	}
+
+	return &HTTPOCRClient{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Name 返回 OCR 后端名称，用于区分数据集中不同后端的识别准确率
+func (c *HTTPOCRClient) Name() string {
+	return c.cfg.Name
+}
+
+// Recognize 调用 OCR API 识别验证码
+func (c *HTTPOCRClient) Recognize(ctx context.Context, imageData []byte) (string, error) {
+	var (
+		body        io.Reader
+		contentType string
+		err         error
+	)
+	switch c.cfg.Mode {
+	case OCRModeBase64:
+		body, contentType, err = c.base64Body(imageData)
+	case OCRModeFile:
+		body, contentType, err = c.multipartBody(imageData)
+	default:
+		return "", fmt.Errorf("不支持的 OCR 上传方式: %s", c.cfg.Mode)
+	}
+	if err != nil {
+		return "", fmt.Errorf("构建请求体失败: %w", err)
+	}
+
+	// 发送请求到 OCR API
+	req, err := http.NewRequestWithContext(ctx, "POST", c.cfg.APIURL+c.cfg.Endpoint, body)
+	if err != nil {
+		return "", fmt.Errorf("创建 OCR 请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("OCR API 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OCR API 返回错误状态码: %d", resp.StatusCode)
+	}
+
+	// 解析响应
+	var result any
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("解析 OCR 响应失败: %w", err)
+	}
+
+	if !c.succeeded(result) {
+		message, _ := lookupField(result, c.cfg.MessageField)
+		return "", fmt.Errorf("OCR 识别失败: %s", fieldString(message))
+	}
+
+	text, ok := lookupField(result, c.cfg.TextField)
+	if !ok {
+		return "", fmt.Errorf("OCR 响应缺少字段: %s", c.cfg.TextField)
+	}
+	return fieldString(text), nil
+}
+
+// base64Body 构建 JSON 请求体，图片以 base64 写入 ImageField 指定的路径
+func (c *HTTPOCRClient) base64Body(imageData []byte) (io.Reader, string, error) {
+	payload := make(map[string]any)
+	setField(payload, c.cfg.ImageField, base64.StdEncoding.EncodeToString(imageData))
+
+	jsonBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", err
+	}
+	return bytes.NewReader(jsonBody), "application/json", nil
+}
+
+// multipartBody 构建 multipart 表单，图片作为 ImageField 指定的文件字段上传
+func (c *HTTPOCRClient) multipartBody(imageData []byte) (io.Reader, string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	part, err := writer.CreateFormFile(c.cfg.ImageField, "captcha"+captcha.ImageExt(imageData))
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := part.Write(imageData); err != nil {
+		return nil, "", err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return &buf, writer.FormDataContentType(), nil
+}
+
+// succeeded 根据 SuccessField / SuccessValue 判断识别是否成功
+func (c *HTTPOCRClient) succeeded(result any) bool {
+	if c.cfg.SuccessField == "-" {
+		return true
+	}
+
+	value, ok := lookupField(result, c.cfg.SuccessField)
+	if !ok {
+		return false
+	}
+	if c.cfg.SuccessValue != "" {
+		return fieldString(value) == c.cfg.SuccessValue
+	}
+	flag, isBool := value.(bool)
+	return isBool && flag
+}
+
+// lookupField 按点号分隔的路径在 JSON 解码结果中取值，数字段用于访问数组下标
+func lookupField(data any, path string) (any, bool) {
+	current := data
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]any:
+			value, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// setField 按点号分隔的路径写入值，中间层级自动创建
+func setField(data map[string]any, path string, value any) {
+	keys := strings.Split(path, ".")
+	node := data
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := node[key].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			node[key] = child
+		}
+		node = child
+	}
+	node[keys[len(keys)-1]] = value
+}
+
+func fieldString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
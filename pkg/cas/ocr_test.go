@@ -0,0 +1,170 @@
+package cas
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var captchaPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestHTTPOCRClientFieldPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      OCRConfig
+		response string
+		// checkRequest 校验 OCR 服务收到的请求
+		checkRequest func(t *testing.T, r *http.Request)
+		want         string
+		wantErr      string
+	}{
+		{
+			name:     "ddddocr defaults",
+			cfg:      OCRConfig{},
+			response: `{"success": true, "data": {"text": " ab12 "}}`,
+			checkRequest: func(t *testing.T, r *http.Request) {
+				if r.URL.Path != "/ocr/base64" {
+					t.Errorf("path = %s, want /ocr/base64", r.URL.Path)
+				}
+				checkBase64Field(t, r, "image")
+			},
+			want: "ab12",
+		},
+		{
+			name: "nested request and array response",
+			cfg: OCRConfig{
+				Endpoint:     "/v1/recognize",
+				ImageField:   "payload.img",
+				TextField:    "result.0.words",
+				SuccessField: "code",
+				SuccessValue: "0",
+			},
+			response: `{"code": 0, "result": [{"words": "xy34"}, {"words": "ignored"}]}`,
+			checkRequest: func(t *testing.T, r *http.Request) {
+				if r.URL.Path != "/v1/recognize" {
+					t.Errorf("path = %s, want /v1/recognize", r.URL.Path)
+				}
+				checkBase64Field(t, r, "payload.img")
+			},
+			want: "xy34",
+		},
+		{
+			name:     "success check disabled",
+			cfg:      OCRConfig{TextField: "text", SuccessField: "-"},
+			response: `{"text": 1234}`,
+			want:     "1234",
+		},
+		{
+			name: "file upload",
+			cfg:  OCRConfig{Mode: OCRModeFile, ImageField: "img"},
+			checkRequest: func(t *testing.T, r *http.Request) {
+				if r.URL.Path != "/ocr/file" {
+					t.Errorf("path = %s, want /ocr/file", r.URL.Path)
+				}
+				file, header, err := r.FormFile("img")
+				if err != nil {
+					t.Fatalf("读取上传文件失败: %v", err)
+				}
+				defer file.Close()
+				content, _ := io.ReadAll(file)
+				if header.Filename != "captcha.png" || string(content) != string(captchaPNG) {
+					t.Errorf("uploaded %s (%d bytes)", header.Filename, len(content))
+				}
+			},
+			response: `{"success": true, "data": {"text": "cd56"}}`,
+			want:     "cd56",
+		},
+		{
+			name:     "failure message",
+			cfg:      OCRConfig{MessageField: "error.msg"},
+			response: `{"success": false, "error": {"msg": "图片无效"}}`,
+			wantErr:  "图片无效",
+		},
+		{
+			name:     "success value mismatch",
+			cfg:      OCRConfig{SuccessField: "status", SuccessValue: "ok"},
+			response: `{"status": "error", "data": {"text": "ab12"}}`,
+			wantErr:  "OCR 识别失败",
+		},
+		{
+			name:     "missing text field",
+			cfg:      OCRConfig{TextField: "data.result"},
+			response: `{"success": true, "data": {"text": "ab12"}}`,
+			wantErr:  "缺少字段: data.result",
+		},
+		{
+			name:     "array index out of range",
+			cfg:      OCRConfig{TextField: "result.1", SuccessField: "-"},
+			response: `{"result": ["only"]}`,
+			wantErr:  "缺少字段: result.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.checkRequest != nil {
+					tt.checkRequest(t, r)
+				}
+				io.WriteString(w, tt.response)
+			}))
+			defer server.Close()
+
+			cfg := tt.cfg
+			cfg.APIURL = server.URL + "/"
+			got, err := NewHTTPOCRClient(cfg).Recognize(context.Background(), captchaPNG)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Recognize() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Recognize() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Recognize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// checkBase64Field 校验 JSON 请求体在 path 处携带 base64 编码的验证码图片
+func checkBase64Field(t *testing.T, r *http.Request, path string) {
+	t.Helper()
+	if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %s, want application/json", ct)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Fatalf("解析请求体失败: %v", err)
+	}
+	value, ok := lookupField(body, path)
+	if !ok {
+		t.Fatalf("请求体缺少字段 %s: %v", path, body)
+	}
+	if value != base64.StdEncoding.EncodeToString(captchaPNG) {
+		t.Errorf("%s = %v, want base64 image", path, value)
+	}
+}
+
+func TestNewHTTPOCRClientName(t *testing.T) {
+	tests := []struct {
+		cfg  OCRConfig
+		want string
+	}{
+		{OCRConfig{APIURL: "http://ocr.local:5000/"}, "http://ocr.local:5000/ocr/base64"},
+		{OCRConfig{APIURL: "http://ocr.local", Mode: OCRModeFile}, "http://ocr.local/ocr/file"},
+		{OCRConfig{APIURL: "http://ocr.local", Name: "paddle"}, "paddle"},
+	}
+	for _, tt := range tests {
+		if got := NewHTTPOCRClient(tt.cfg).Name(); got != tt.want {
+			t.Errorf("Name() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package cas
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// LoginErrorType 登录失败类型
+type LoginErrorType string
+
+const (
+	LoginErrorCaptcha         LoginErrorType = "captcha"          // 验证码错误，可立即重试
+	LoginErrorPassword        LoginErrorType = "password"         // 账号或密码错误
+	LoginErrorLocked          LoginErrorType = "locked"           // 账号被锁定
+	LoginErrorPasswordExpired LoginErrorType = "password_expired" // 密码需要修改（初始密码、过期或强度不足）
+	LoginErrorOutOfService    LoginErrorType = "out_of_service"   // 不在系统开放时间
+	LoginErrorMaintenance     LoginErrorType = "maintenance"      // 系统维护中
+	LoginErrorUnknown         LoginErrorType = "unknown"          // 未识别的登录结果
+)
+
+// excerptRunes 登录错误中保留的页面摘要长度（字符数）
+const excerptRunes = 160
+
+// LoginError 登录错误类型
+type LoginError struct {
+	Type    LoginErrorType
+	Message string
+	Excerpt string // 响应页面的文本摘要，便于排查未识别的提示
+}
+
+func (e *LoginError) Error() string {
+	if e.Excerpt == "" {
+		return e.Message
+	}
+	return e.Message + "，页面摘要: " + e.Excerpt
+}
+
+// IsCaptchaError 判断是否为验证码错误
+func IsCaptchaError(err error) bool {
+	return LoginErrorTypeOf(err) == LoginErrorCaptcha
+}
+
+// IsPasswordError 判断是否为密码错误
+func IsPasswordError(err error) bool {
+	return LoginErrorTypeOf(err) == LoginErrorPassword
+}
+
+// LoginErrorTypeOf 返回错误链中 LoginError 的类型，不是登录错误时返回空字符串
+func LoginErrorTypeOf(err error) LoginErrorType {
+	var loginErr *LoginError
+	if errors.As(err, &loginErr) {
+		return loginErr.Type
+	}
+	return ""
+}
+
+// loginResultRule 按页面关键字识别登录失败类型，按顺序匹配
+type loginResultRule struct {
+	errType  LoginErrorType
+	message  string
+	keywords []string
+	// messageOnly 为 true 时只匹配服务端提示信息，避免被登录页上的
+	// “修改密码”“开放时间”“系统维护”等公告或静态文字误判。
+	// 仅验证码错误允许匹配整页文本，误判时只会多重试一次
+	messageOnly bool
+}
+
+// loginResultRules 的关键字均为服务端提示的完整短语：密码错误与需要修改密码
+// 会被写入失败台账并永久封锁当前凭据，不能被“请输入用户名或密码”之类的前端校验提示触发
+var loginResultRules = []loginResultRule{
+	{LoginErrorCaptcha, "验证码错误", []string{"验证码错误", "验证码不正确", "验证码已过期"}, false},
+	{LoginErrorLocked, "账号已被锁定", []string{"已被锁定", "已锁定", "已被冻结", "已被禁用", "登录失败次数过多"}, true},
+	{LoginErrorPasswordExpired, "密码需要修改", []string{"密码已过期", "请修改初始密码", "请修改密码后", "密码过于简单", "密码强度不足"}, true},
+	{LoginErrorPassword, "用户名或密码错误", []string{"用户名或密码错误", "账号或密码错误", "帐号或密码错误", "密码错误", "密码不正确", "用户不存在", "账号不存在", "帐号不存在"}, true},
+	{LoginErrorMaintenance, "教务系统维护中", []string{"系统维护中", "系统正在维护", "系统正在升级"}, true},
+	{LoginErrorOutOfService, "不在教务系统开放时间", []string{"不在开放时间", "不在访问时间", "未到开放时间", "系统暂未开放"}, true},
+}
+
+// messagePattern 强智登录页展示服务端提示信息的元素
+var messagePattern = regexp.MustCompile(`(?is)<[^>]+id=["']?(?:showMsg|errorinfo)["']?[^>]*>(.*?)</`)
+
+// classifyLoginResult 根据登录响应页面识别失败类型，未命中任何规则时返回 nil。
+// 优先匹配页面中的提示信息，整页文本只用于识别验证码错误。
+func classifyLoginResult(respBody string) *LoginError {
+	if message := pageMessage(respBody); message != "" {
+		if loginErr := matchLoginRules(message, true); loginErr != nil {
+			return loginErr
+		}
+	}
+	return matchLoginRules(pageText(respBody), false)
+}
+
+func matchLoginRules(text string, isMessage bool) *LoginError {
+	for _, rule := range loginResultRules {
+		if rule.messageOnly && !isMessage {
+			continue
+		}
+		for _, keyword := range rule.keywords {
+			if idx := strings.Index(text, keyword); idx >= 0 {
+				return &LoginError{
+					Type:    rule.errType,
+					Message: rule.message,
+					Excerpt: excerptAround(text, idx, len(keyword)),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// pageMessage 提取页面中的服务端提示信息：showMsg / errorinfo 元素的文本，
+// 以及内联脚本在页面加载时弹出的 alert
+func pageMessage(body string) string {
+	var parts []string
+	for _, match := range messagePattern.FindAllStringSubmatch(body, -1) {
+		if text := pageText(match[1]); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	for _, match := range inlineScriptPattern.FindAllStringSubmatch(body, -1) {
+		if srcAttrPattern.MatchString(match[1]) {
+			continue
+		}
+		for _, alert := range loadAlerts(match[2]) {
+			if text := pageText(alert); text != "" {
+				parts = append(parts, text)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+var (
+	inlineScriptPattern = regexp.MustCompile(`(?is)<script([^>]*)>(.*?)</script>`)
+	srcAttrPattern      = regexp.MustCompile(`(?i)\bsrc\s*=`)
+	alertCallPattern    = regexp.MustCompile(`^alert\(\s*(?:"([^"]*)"|'([^']*)')`)
+
+	// functionBodyPattern 匹配 { 之前的函数头，onloadBodyPattern 匹配页面加载时执行的函数头
+	functionBodyPattern = regexp.MustCompile(`(?:\bfunction\s*[\w$]*\s*\([^()]*\)|=>)\s*$`)
+	onloadBodyPattern   = regexp.MustCompile(`(?:(?:\$|\bjQuery)\(\s*(?:document\s*\)\s*\.ready\(\s*)?|\bonload\s*=\s*)function\s*\(\s*\)\s*$`)
+)
+
+// loadAlerts 返回脚本在页面加载时执行的 alert 提示。
+// 函数体中的 alert（例如提交前校验的“请输入用户名或密码”）只在用户操作时弹出，予以忽略；
+// $(function(){...})、$(document).ready、window.onload 的函数体仍视为加载时执行
+func loadAlerts(code string) []string {
+	var alerts []string
+	// opaque 记录每层花括号是否为不在加载时执行的函数体
+	var opaque []bool
+	skipped := 0 // 外层函数体的层数
+	for i := 0; i < len(code); i++ {
+		switch c := code[i]; {
+		case c == '"' || c == '\'' || c == '`':
+			i = skipString(code, i)
+		case strings.HasPrefix(code[i:], "//"):
+			if end := strings.IndexByte(code[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(code)
+			}
+		case strings.HasPrefix(code[i:], "/*"):
+			if end := strings.Index(code[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(code)
+			}
+		case c == '{':
+			head := code[max(0, i-80):i]
+			isFunc := functionBodyPattern.MatchString(head) && !onloadBodyPattern.MatchString(head)
+			opaque = append(opaque, isFunc)
+			if isFunc {
+				skipped++
+			}
+		case c == '}':
+			if n := len(opaque); n > 0 {
+				if opaque[n-1] {
+					skipped--
+				}
+				opaque = opaque[:n-1]
+			}
+		case c == 'a' && skipped == 0 && (i == 0 || !isIdentByte(code[i-1])):
+			if match := alertCallPattern.FindStringSubmatch(code[i:]); match != nil {
+				alerts = append(alerts, match[1]+match[2])
+			}
+		}
+	}
+	return alerts
+}
+
+// skipString 返回从 start 开始的字符串字面量的结束引号位置
+func skipString(code string, start int) int {
+	quote := code[start]
+	for i := start + 1; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		}
+	}
+	return len(code)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// unknownLoginError 构造未识别的登录结果错误，附带页面开头的摘要
+func unknownLoginError(message, respBody string) *LoginError {
+	return &LoginError{
+		Type:    LoginErrorUnknown,
+		Message: message,
+		Excerpt: excerptAround(pageText(respBody), 0, 0),
+	}
+}
+
+var (
+	scriptPattern     = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	tagPattern        = regexp.MustCompile(`(?s)<[^>]*>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// pageText 去除 HTML 标签、脚本与多余空白，得到页面可见文本
+func pageText(body string) string {
+	text := scriptPattern.ReplaceAllString(body, " ")
+	text = tagPattern.ReplaceAllString(text, " ")
+	text = html.UnescapeString(text)
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(text, " "))
+}
+
+// excerptAround 截取 text 中从字节位置 idx 开始、长度为 n 的片段前后的文本
+func excerptAround(text string, idx, n int) string {
+	before := []rune(text[:idx])
+	after := []rune(text[idx:])
+
+	start := len(before) - excerptRunes/4
+	if start < 0 {
+		start = 0
+	}
+	limit := excerptRunes - (len(before) - start)
+	if keyword := len([]rune(text[idx : idx+n])); limit < keyword {
+		limit = keyword
+	}
+	if limit > len(after) {
+		limit = len(after)
+	}
+
+	excerpt := string(before[start:]) + string(after[:limit])
+	if start > 0 {
+		excerpt = "…" + excerpt
+	}
+	if limit < len(after) {
+		excerpt += "…"
+	}
+	return excerpt
+}
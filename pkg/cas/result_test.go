@@ -0,0 +1,161 @@
+package cas
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// logonPage 模拟 Logon.do 重新返回的登录页：带前端校验脚本、登录须知与提示区域，
+// msg 为 showMsg 中的服务端提示，script 为额外的内联脚本
+func logonPage(msg, script string) string {
+	return `<!DOCTYPE html>
+<html>
+<head>
+<meta http-equiv="Content-Type" content="text/html; charset=utf-8">
+<title>曲阜师范大学教学综合信息服务平台</title>
+<script type="text/javascript" src="/jsxsd/js/jquery-min.js"></script>
+<script type="text/javascript" src="/jsxsd/js/alert('用户名或密码错误').js"></script>
+<script type="text/javascript">
+	var contextPath = "/jsxsd";
+	function submitForm1() {
+		var userAccount = document.getElementById("userAccount").value;
+		var userPassword = document.getElementById("userPassword").value;
+		if (userAccount == "" || userPassword == "") {
+			alert("请输入用户名或密码");
+			return false;
+		}
+		if (document.getElementById("RANDOMCODE").value == "") {
+			alert('请输入验证码');
+			return false;
+		}
+		var encoded = encodeInp(userAccount) + "%%%" + encodeInp(userPassword);
+		document.getElementById("encoded").value = encoded;
+		return true;
+	}
+	var reloadCode = function() {
+		alert("验证码已过期，请点击刷新");
+	};
+	$(function() {
+		$("#SafeCodeImg").click(function() { alert("账号已被锁定"); });
+		// alert("用户名或密码错误");
+		$("#userAccount").focus();
+	});
+</script>
+` + script + `
+</head>
+<body>
+<div class="tips">
+	<p>首次登录请使用初始密码，登录后请及时修改密码。</p>
+	<p>系统开放时间为 8:00-22:00，系统维护期间暂停服务。</p>
+</div>
+<form action="/jsxsd/xk/LoginToXk" method="post" onsubmit="return submitForm1()">
+	<input type="text" id="userAccount" name="userAccount" onblur="if(this.value==''){alert('用户名或密码不能为空')}">
+	<input type="password" id="userPassword" name="userPassword">
+	<input type="hidden" id="encoded" name="encoded">
+	<input type="text" id="RANDOMCODE" name="RANDOMCODE">
+	<img id="SafeCodeImg" src="/jsxsd/verifycode.servlet">
+	<font style="display: inline;white-space:nowrap;" color="red" id="showMsg">` + msg + `</font>
+	<font color="red">温馨提示：忘记密码请联系学院教务办修改密码</font>
+</form>
+</body>
+</html>`
+}
+
+func TestClassifyLoginResult(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want LoginErrorType // 空字符串表示未识别
+	}{
+		{
+			name: "login page with validation script only",
+			body: logonPage("", ""),
+		},
+		{
+			name: "captcha error in showMsg",
+			body: logonPage("验证码错误!!", ""),
+			want: LoginErrorCaptcha,
+		},
+		{
+			name: "wrong password in showMsg",
+			body: logonPage("用户名或密码错误", ""),
+			want: LoginErrorPassword,
+		},
+		{
+			name: "unknown account alerted on load",
+			body: logonPage("", `<script type="text/javascript">alert("该帐号不存在或密码错误,请联系管理员!");window.location.href="/jsxsd/";</script>`),
+			want: LoginErrorPassword,
+		},
+		{
+			name: "alert inside ready handler",
+			body: logonPage("", `<script>$(document).ready(function() { if (true) { alert('您的密码已过期，请修改密码后重新登录'); } });</script>`),
+			want: LoginErrorPasswordExpired,
+		},
+		{
+			name: "locked account in errorinfo",
+			body: `<html><body><div id="errorinfo" class="err">该账号已被锁定，请 30 分钟后再试</div></body></html>`,
+			want: LoginErrorLocked,
+		},
+		{
+			name: "out of service",
+			body: logonPage("当前不在访问时间内，请在开放时间登录", ""),
+			want: LoginErrorOutOfService,
+		},
+		{
+			name: "maintenance alert in onload",
+			body: logonPage("", `<script>window.onload = function() { alert("系统维护中，请稍后访问"); };</script>`),
+			want: LoginErrorMaintenance,
+		},
+		{
+			name: "captcha error outside message area",
+			body: `<html><body><p>验证码错误，请重新输入</p></body></html>`,
+			want: LoginErrorCaptcha,
+		},
+		{
+			name: "weak password phrase outside message area",
+			body: `<html><body><p>密码错误次数过多将被锁定，请修改初始密码</p></body></html>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyLoginResult(tt.body)
+			var gotType LoginErrorType
+			if got != nil {
+				gotType = got.Type
+			}
+			if gotType != tt.want {
+				t.Errorf("classifyLoginResult() = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadAlerts(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want []string
+	}{
+		{"top level", `alert("a"); window.alert('b');`, []string{"a", "b"}},
+		{"named function", `function check() { alert("x"); }`, nil},
+		{"function expression and arrow", `var f = function (e) { alert("x") }; g(() => { alert("y") });`, nil},
+		{"jquery ready", `jQuery(function() { alert("a"); $("#b").click(function() { alert("x"); }); });`, []string{"a"}},
+		{"control block", `if (msg != "") { alert("a"); } else { alert("b"); }`, []string{"a", "b"}},
+		{"strings and comments", `var s = "alert('x') }"; /* alert("y") */ myalert("z"); alert("a")`, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := loadAlerts(tt.code); !slices.Equal(got, tt.want) {
+				t.Errorf("loadAlerts(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginErrorExcerpt(t *testing.T) {
+	loginErr := classifyLoginResult(logonPage("用户名或密码错误", ""))
+	if loginErr == nil || !strings.Contains(loginErr.Excerpt, "用户名或密码错误") {
+		t.Errorf("Excerpt = %v, want containing the server message", loginErr)
+	}
+}
@@ -1,64 +1,260 @@
 package cas
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/auth"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/logger"
 	"github.com/juju/persistent-cookiejar"
 )
 
 const (
-	defaultSessionPath = "data/cookies.json"
+	DefaultDataDir  = "data"
+	sessionFileName = "cookies.json"
+
+	// 会话级 Cookie（无 Expires）持久化时补上的有效期
+	sessionCookieTTL = 24 * time.Hour
+
+	// 加密 session 文件的格式标识，其后为 base64(nonce + 密文)
+	encryptedSessionPrefix = "QXKSESSION1:"
 )
 
-// newPersistentJar 创建一个新的可持久化 CookieJar，
-// 若文件已存在则自动加载先前保存的 Cookie。
-func newPersistentJar() (*cookiejar.Jar, error) {
-	dir := filepath.Dir(defaultSessionPath)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return nil, fmt.Errorf("创建 session 目录失败: %w", err)
+var unsafeAccountChars = regexp.MustCompile(`[^0-9A-Za-z_.-]+`)
+
+// AccountDir 返回账号专属的数据目录: <dataDir>/<username>。
+// username 为空时直接返回 dataDir，以兼容未区分账号的旧版布局。
+func AccountDir(dataDir, username string) string {
+	if dataDir == "" {
+		dataDir = DefaultDataDir
+	}
+	name := unsafeAccountChars.ReplaceAllString(username, "_")
+	if name == "" || name == "." || name == ".." {
+		return dataDir
+	}
+	return filepath.Join(dataDir, name)
+}
+
+// MigrateLegacyFile 将旧版放在数据目录根部的文件迁移到账号目录。
+// 仅在旧文件存在且新文件不存在时迁移，返回是否发生了迁移。
+func MigrateLegacyFile(legacyPath, newPath string) (bool, error) {
+	if filepath.Clean(legacyPath) == filepath.Clean(newPath) {
+		return false, nil
+	}
+	if _, err := os.Stat(newPath); err == nil {
+		return false, nil
+	}
+	if _, err := os.Stat(legacyPath); err != nil {
+		return false, nil
 	}
 
-	jar, err := cookiejar.New(&cookiejar.Options{
-		Filename: defaultSessionPath,
-	})
+	if err := os.MkdirAll(filepath.Dir(newPath), 0o700); err != nil {
+		return false, fmt.Errorf("创建账号数据目录失败: %w", err)
+	}
+	if err := os.Rename(legacyPath, newPath); err != nil {
+		return false, fmt.Errorf("迁移 %s 失败: %w", legacyPath, err)
+	}
+	return true, nil
+}
+
+// storedCookie 是 session 文件中的单条 Cookie。
+// 字段名与 persistent-cookiejar 的存储格式一致，以便直接读取旧版明文文件。
+type storedCookie struct {
+	Name     string
+	Value    string
+	Domain   string
+	Path     string
+	Secure   bool
+	HttpOnly bool
+	HostOnly bool
+	Expires  time.Time
+}
+
+// sessionStore 负责 session 文件的读写。
+// 配置密钥时使用 AES-GCM 加密存储，否则以明文 JSON 存储；文件权限均为 0600。
+type sessionStore struct {
+	path string
+	key  []byte
+}
+
+// newMemoryJar 创建一个不自动读写文件的 CookieJar，持久化统一由 sessionStore 负责。
+func newMemoryJar() (*cookiejar.Jar, error) {
+	jar, err := cookiejar.New(&cookiejar.Options{NoPersist: true})
 	if err != nil {
 		return nil, fmt.Errorf("创建 CookieJar 失败: %w", err)
 	}
 	return jar, nil
 }
 
-// renewSessionCookies 为所有会话级 Cookie（无 Expires）补上过期时间，
-// 使 persistent-cookiejar 能将其持久化到文件。
-func renewSessionCookies(jar *cookiejar.Jar) {
-	expires := time.Now().Add(24 * time.Hour)
-	allCookies := jar.AllCookies()
-
-	// 按 Domain 分组，重新写入 jar
-	grouped := make(map[string][]*http.Cookie)
-	for _, c := range allCookies {
-		if c.Expires.IsZero() {
-			c.Expires = expires
+// save 将 Cookie 写入 session 文件。
+// 会话级 Cookie 补上 sessionCookieTTL 的有效期，否则重启后无法复用。
+func (s *sessionStore) save(jar *cookiejar.Jar) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return 0, fmt.Errorf("创建 session 目录失败: %w", err)
+	}
+
+	now := time.Now()
+	cookies := jar.AllCookies()
+	stored := make([]storedCookie, 0, len(cookies))
+	for _, ck := range cookies {
+		expires := ck.Expires
+		if expires.IsZero() || expires.Sub(now) > 100*365*24*time.Hour {
+			expires = now.Add(sessionCookieTTL)
+		}
+		stored = append(stored, storedCookie{
+			Name:     ck.Name,
+			Value:    ck.Value,
+			Domain:   ck.Domain,
+			Path:     ck.Path,
+			Secure:   ck.Secure,
+			HttpOnly: ck.HttpOnly,
+			HostOnly: isHostOnly(jar, ck),
+			Expires:  expires,
+		})
+	}
+
+	content, err := json.Marshal(stored)
+	if err != nil {
+		return 0, fmt.Errorf("序列化 session 失败: %w", err)
+	}
+
+	if s.key != nil {
+		encrypted, err := auth.Encrypt(s.key, content)
+		if err != nil {
+			return 0, fmt.Errorf("加密 session 失败: %w", err)
+		}
+		content = []byte(encryptedSessionPrefix + base64.StdEncoding.EncodeToString(encrypted))
+	}
+
+	return len(stored), writePrivateFile(s.path, content)
+}
+
+// isHostOnly 判断 Cookie 是否只发送给设置它的主机。
+// AllCookies 不返回 HostOnly 标记，这里用该域名的子域名向 jar 查询：
+// 域 Cookie 会发送给子域名，只对主机生效的 Cookie 则不会。
+func isHostOnly(jar *cookiejar.Jar, ck *http.Cookie) bool {
+	path := ck.Path
+	if path == "" {
+		path = "/"
+	}
+	probe := &url.URL{Scheme: "https", Host: "host-only-probe." + strings.TrimPrefix(ck.Domain, "."), Path: path}
+	for _, sent := range jar.Cookies(probe) {
+		if sent.Name == ck.Name && sent.Value == ck.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// load 读取 session 文件并返回未过期的 Cookie，文件不存在时返回 os.ErrNotExist。
+// 兼容旧版 persistent-cookiejar 写入的明文文件。
+func (s *sessionStore) load() ([]storedCookie, error) {
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if rest, ok := bytes.CutPrefix(bytes.TrimSpace(content), []byte(encryptedSessionPrefix)); ok {
+		if s.key == nil {
+			return nil, errors.New("session 文件已加密，但未配置 SESSION_KEY 或 SESSION_KEY_FILE")
+		}
+		encrypted, err := base64.StdEncoding.DecodeString(string(rest))
+		if err != nil {
+			return nil, fmt.Errorf("解码 session 文件失败: %w", err)
+		}
+		if content, err = auth.Decrypt(s.key, encrypted); err != nil {
+			return nil, err
+		}
+	} else if s.key != nil {
+		log.Printf("[WARN] session 文件为明文，将在下次保存时加密: %s", s.path)
+	}
+
+	var stored []storedCookie
+	if len(bytes.TrimSpace(content)) > 0 {
+		if err := json.Unmarshal(content, &stored); err != nil {
+			return nil, fmt.Errorf("解析 session 文件失败: %w", err)
 		}
-		grouped[c.Domain] = append(grouped[c.Domain], c)
 	}
 
-	for domain, cookies := range grouped {
+	now := time.Now()
+	valid := stored[:0]
+	for _, ck := range stored {
+		if ck.Expires.After(now) {
+			valid = append(valid, ck)
+		}
+	}
+	return valid, nil
+}
+
+// clear 删除 session 文件
+func (s *sessionStore) clear() {
+	_ = os.Remove(s.path)
+}
+
+// restoreCookies 将 session 文件中的 Cookie 写回 jar
+func restoreCookies(jar *cookiejar.Jar, stored []storedCookie) {
+	for _, ck := range stored {
 		scheme := "http"
-		for _, c := range cookies {
-			if c.Secure {
-				scheme = "https"
-				break
-			}
+		if ck.Secure {
+			scheme = "https"
+		}
+		cookie := &http.Cookie{
+			Name:     ck.Name,
+			Value:    ck.Value,
+			Path:     ck.Path,
+			Secure:   ck.Secure,
+			HttpOnly: ck.HttpOnly,
+			Expires:  ck.Expires,
+		}
+		if !ck.HostOnly {
+			cookie.Domain = ck.Domain
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: ck.Domain, Path: "/"}, []*http.Cookie{cookie})
+	}
+}
+
+// writePrivateFile 以 0600 权限原子写入文件
+func writePrivateFile(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	if err := tmp.Chmod(0o600); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("设置文件权限失败: %w", err)
+	}
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(path)
+		if err2 := os.Rename(tmpPath, path); err2 != nil {
+			_ = os.Remove(tmpPath)
+			return fmt.Errorf("替换文件失败: %w", err2)
 		}
-		u := &url.URL{Scheme: scheme, Host: domain, Path: "/"}
-		jar.SetCookies(u, cookies)
 	}
+	return os.Chmod(path, 0o600)
 }
 
 // SaveSession 将当前 CookieJar 中的所有 Cookie 持久化到文件
@@ -68,49 +264,51 @@ func (c *Client) SaveSession() error {
 		return fmt.Errorf("当前 CookieJar 不支持序列化")
 	}
 
-	// 为会话级 Cookie 续命，否则 persistent-cookiejar 不会保存它们
-	renewSessionCookies(jar)
-
-	if err := jar.Save(); err != nil {
+	count, err := c.store.save(jar)
+	if err != nil {
 		return fmt.Errorf("保存 session 失败: %w", err)
 	}
-	log.Printf("[INFO] Session 已保存到: %s (共 %d 个 Cookie)", defaultSessionPath, len(jar.AllCookies()))
+
+	mode := "加密"
+	if c.store.key == nil {
+		mode = "明文"
+	}
+	log.Printf("[INFO] Session 已保存到: %s (共 %d 个 Cookie, %s存储)", c.store.path, count, mode)
 	return nil
 }
 
 // LoadSession 从文件加载 Cookie 到当前 CookieJar。
 // 返回 true 表示成功加载，false 表示没有可用的 session。
 func (c *Client) LoadSession() bool {
-	if _, err := os.Stat(defaultSessionPath); err != nil {
-		if !os.IsNotExist(err) {
-			log.Printf("[WARN] 检查 session 文件失败: %v", err)
+	stored, err := c.store.load()
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[WARN] 加载 session 失败: %v", err)
 		}
 		return false
 	}
-
-	jar, err := newPersistentJar()
-	if err != nil {
-		log.Printf("[WARN] 加载 session 失败: %v", err)
+	if len(stored) == 0 {
+		log.Printf("[WARN] session 文件存在但无有效 Cookie")
 		return false
 	}
 
-	allCookies := jar.AllCookies()
-	if len(allCookies) == 0 {
-		log.Printf("[WARN] session 文件存在但无有效 Cookie")
+	jar, err := newMemoryJar()
+	if err != nil {
+		log.Printf("[WARN] 加载 session 失败: %v", err)
 		return false
 	}
-
+	restoreCookies(jar, stored)
 	c.httpClient.Jar = jar
 
-	log.Printf("[INFO] 已从文件加载 session: %s (共 %d 个 Cookie)", defaultSessionPath, len(allCookies))
-	for _, ck := range allCookies {
+	log.Printf("[INFO] 已从文件加载 session: %s (共 %d 个 Cookie)", c.store.path, len(stored))
+	for _, ck := range stored {
 		log.Printf("[DEBUG] Cookie: %s=%s (Domain=%s, Path=%s, Expires=%s)",
-			ck.Name, ck.Value, ck.Domain, ck.Path, ck.Expires.Format("2006-01-02 15:04:05"))
+			ck.Name, logger.Redact(ck.Value), ck.Domain, ck.Path, ck.Expires.Format("2006-01-02 15:04:05"))
 	}
 	return true
 }
 
 // ClearSession 删除保存的 session 文件
-func ClearSession() {
-	_ = os.Remove(defaultSessionPath)
+func (c *Client) ClearSession() {
+	c.store.clear()
 }
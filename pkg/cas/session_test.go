@@ -0,0 +1,139 @@
+package cas
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAccountDir(t *testing.T) {
+	tests := []struct {
+		dataDir, username string
+		want              string
+	}{
+		{"data", "2021001", filepath.Join("data", "2021001")},
+		{"", "2021001", filepath.Join(DefaultDataDir, "2021001")},
+		{"data", "", "data"},
+		{"data", "../etc", filepath.Join("data", ".._etc")},
+		{"data", "..", "data"},
+		{"data", "a b/c", filepath.Join("data", "a_b_c")},
+	}
+	for _, tt := range tests {
+		if got := AccountDir(tt.dataDir, tt.username); got != tt.want {
+			t.Errorf("AccountDir(%q, %q) = %q, want %q", tt.dataDir, tt.username, got, tt.want)
+		}
+	}
+}
+
+func TestMigrateLegacyFile(t *testing.T) {
+	tests := []struct {
+		name         string
+		legacy       string // 旧版文件内容，空表示不存在
+		existing     string // 账号目录中已有的文件内容，空表示不存在
+		wantMigrated bool
+		want         string // 迁移后账号目录中的文件内容
+	}{
+		{name: "legacy only", legacy: "old", wantMigrated: true, want: "old"},
+		{name: "both exist keeps new file", legacy: "old", existing: "new", want: "new"},
+		{name: "nothing to migrate"},
+	}
+	for _, tt := range tests {
+		for _, file := range []string{"cookies.json", "last_result.json"} {
+			t.Run(tt.name+"/"+file, func(t *testing.T) {
+				dataDir := t.TempDir()
+				legacyPath := filepath.Join(dataDir, file)
+				newPath := filepath.Join(AccountDir(dataDir, "2021001"), file)
+				if tt.legacy != "" {
+					writeFile(t, legacyPath, tt.legacy)
+				}
+				if tt.existing != "" {
+					writeFile(t, newPath, tt.existing)
+				}
+
+				migrated, err := MigrateLegacyFile(legacyPath, newPath)
+				if err != nil {
+					t.Fatalf("MigrateLegacyFile() error = %v", err)
+				}
+				if migrated != tt.wantMigrated {
+					t.Errorf("MigrateLegacyFile() = %v, want %v", migrated, tt.wantMigrated)
+				}
+				if got, _ := os.ReadFile(newPath); string(got) != tt.want {
+					t.Errorf("账号目录文件内容 = %q, want %q", got, tt.want)
+				}
+				_, err = os.Stat(legacyPath)
+				if exists, want := err == nil, tt.legacy != "" && !tt.wantMigrated; exists != want {
+					t.Errorf("旧版文件存在 = %v, want %v", exists, want)
+				}
+			})
+		}
+	}
+
+	// 未区分账号时新旧路径相同，不应迁移
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	writeFile(t, path, "old")
+	if migrated, err := MigrateLegacyFile(path, path); migrated || err != nil {
+		t.Errorf("MigrateLegacyFile(same path) = %v, %v, want false, nil", migrated, err)
+	}
+}
+
+func TestNewClientMigratesLegacySession(t *testing.T) {
+	dataDir := t.TempDir()
+	// 旧版 persistent-cookiejar 写入数据目录根部的明文文件
+	legacy, err := json.Marshal([]storedCookie{{
+		Name:    "JSESSIONID",
+		Value:   "legacy-session",
+		Domain:  "zhjw.qfnu.edu.cn",
+		Path:    "/",
+		Expires: time.Now().Add(time.Hour),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dataDir, sessionFileName), string(legacy))
+
+	key := bytes.Repeat([]byte{7}, 32)
+	client, err := NewClient(WithDataDir(dataDir), WithAccount("2021001"), WithSessionKey(key))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	sessionPath := filepath.Join(dataDir, "2021001", sessionFileName)
+	if _, err := os.Stat(sessionPath); err != nil {
+		t.Fatalf("session 文件未迁移到账号目录: %v", err)
+	}
+	if !client.LoadSession() {
+		t.Fatal("LoadSession() = false, want true")
+	}
+	cookies := client.GetClient().Jar.Cookies(&url.URL{Scheme: "http", Host: "zhjw.qfnu.edu.cn", Path: "/"})
+	if len(cookies) != 1 || cookies[0].Value != "legacy-session" {
+		t.Fatalf("cookies = %v, want JSESSIONID=legacy-session", cookies)
+	}
+
+	// 再次保存时明文转为加密存储
+	if err := client.SaveSession(); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+	content, err := os.ReadFile(sessionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(content, []byte(encryptedSessionPrefix)) {
+		t.Errorf("session 文件未加密: %.40q", content)
+	}
+	if info, err := os.Stat(sessionPath); err == nil && info.Mode().Perm() != 0o600 {
+		t.Errorf("session 文件权限 = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
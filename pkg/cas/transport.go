@@ -0,0 +1,170 @@
+package cas
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 100
+)
+
+// transportOptions 保存 HTTP 传输层相关配置
+type transportOptions struct {
+	proxyURL        string
+	roundTripper    http.RoundTripper
+	tlsConfig       *tls.Config
+	userAgent       string
+	maxIdleConns    int
+	maxIdlePerHost  int
+	maxConnsPerHost int
+	wrappers        []func(http.RoundTripper) http.RoundTripper
+}
+
+// WithProxy 通过代理访问教务系统，支持 http://、https:// 与 socks5:// 代理地址
+func WithProxy(proxyURL string) ClientOption {
+	return func(o *clientOptions) {
+		o.transport.proxyURL = strings.TrimSpace(proxyURL)
+	}
+}
+
+// WithTransport 使用自定义的 http.RoundTripper，设置后代理、TLS 与连接池选项不再生效
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(o *clientOptions) {
+		o.transport.roundTripper = rt
+	}
+}
+
+// WithTransportWrapper 在传输层外再包一层（如 WebVPN URL 改写），可多次使用，先添加的在内层
+func WithTransportWrapper(wrap func(http.RoundTripper) http.RoundTripper) ClientOption {
+	return func(o *clientOptions) {
+		if wrap != nil {
+			o.transport.wrappers = append(o.transport.wrappers, wrap)
+		}
+	}
+}
+
+// WithTLSConfig 设置 TLS 配置，例如通过自签 CA 的 https 反向代理访问时使用
+func WithTLSConfig(cfg *tls.Config) ClientOption {
+	return func(o *clientOptions) {
+		o.transport.tlsConfig = cfg
+	}
+}
+
+// WithUserAgent 覆盖请求使用的 User-Agent
+func WithUserAgent(ua string) ClientOption {
+	return func(o *clientOptions) {
+		o.transport.userAgent = strings.TrimSpace(ua)
+	}
+}
+
+// WithConnectionPool 设置连接池大小，小于等于 0 的值保持默认
+func WithConnectionPool(maxIdleConns, maxIdleConnsPerHost, maxConnsPerHost int) ClientOption {
+	return func(o *clientOptions) {
+		if maxIdleConns > 0 {
+			o.transport.maxIdleConns = maxIdleConns
+		}
+		if maxIdleConnsPerHost > 0 {
+			o.transport.maxIdlePerHost = maxIdleConnsPerHost
+		}
+		if maxConnsPerHost > 0 {
+			o.transport.maxConnsPerHost = maxConnsPerHost
+		}
+	}
+}
+
+// LoadTLSConfig 根据 CA 证书文件、SNI 名称与是否跳过校验构建 TLS 配置。
+// caFile 中的证书会追加到系统根证书之上。
+func LoadTLSConfig(caFile, serverName string, insecureSkipVerify bool) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         strings.TrimSpace(serverName),
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	caFile = strings.TrimSpace(caFile)
+	if caFile == "" {
+		return cfg, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取 CA 证书失败: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("CA 证书文件中没有有效的 PEM 证书: %s", caFile)
+	}
+	cfg.RootCAs = pool
+	return cfg, nil
+}
+
+// userAgentTransport 为未设置 User-Agent 的请求（如 jwxt 业务请求）补上统一的 User-Agent
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") != "" {
+		return t.base.RoundTrip(req)
+	}
+	clone := req.Clone(req.Context())
+	clone.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(clone)
+}
+
+// buildTransport 根据选项构建 HTTP 传输层
+func buildTransport(o transportOptions) (http.RoundTripper, error) {
+	rt, err := buildBaseTransport(o)
+	if err != nil {
+		return nil, err
+	}
+	for _, wrap := range o.wrappers {
+		rt = wrap(rt)
+	}
+	if o.userAgent == "" {
+		return rt, nil
+	}
+	return &userAgentTransport{base: rt, userAgent: o.userAgent}, nil
+}
+
+func buildBaseTransport(o transportOptions) (http.RoundTripper, error) {
+	if o.roundTripper != nil {
+		return o.roundTripper, nil
+	}
+
+	transport := &http.Transport{
+		MaxIdleConns:        o.maxIdleConns,
+		MaxIdleConnsPerHost: o.maxIdlePerHost,
+		MaxConnsPerHost:     o.maxConnsPerHost,
+		IdleConnTimeout:     90 * time.Second,
+		TLSClientConfig:     o.tlsConfig,
+	}
+
+	if o.proxyURL != "" {
+		proxy, err := url.Parse(o.proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("代理地址格式错误: %w", err)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("不支持的代理协议: %s", proxy.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	return transport, nil
+}
@@ -2,42 +2,300 @@ package config
 
 import (
 	"fmt"
+	"net/mail"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 const (
+	DefaultDataDir      = "data"
 	DefaultPollInterval = 2
+	DefaultOCRTimeout   = 10
+	DefaultKeepalive    = 300
+	DefaultHARMaxFiles  = 20
+	DefaultHARMaxMB     = 100
+
+	DefaultLoginMaxFailures   = 10
+	DefaultLoginFailureWindow = 30
+
+	DefaultBotMaxSubscriptions      = 5
+	DefaultBotMaxTotalSubscriptions = 100
+	DefaultBotQueryCooldown         = 30
+	DefaultBotGlobalQueryCooldown   = 5
+
+	DefaultOneBotMaxLength       = 1500
+	DefaultOneBotRateLimit       = 20
+	DefaultOneBotTargetRateLimit = 10
+	DefaultOneBotMaxRetries      = 3
+	DefaultOneBotRetryRetCodes   = "103"
+
+	DefaultOutboxMaxAge = 30
+
+	// 推送渠道
+	NotifyChannelOneBot = "onebot"
+	NotifyChannelEmail  = "email"
+
+	// SMTP 加密方式
+	SMTPSecurityStartTLS = "starttls"
+	SMTPSecuritySSL      = "ssl"
+	SMTPSecurityNone     = "none"
+
+	// OneBot 通信方式
+	OneBotModeHTTP      = "http"       // HTTP POST 调用 API
+	OneBotModeWS        = "ws"         // 正向 WebSocket，由本程序连接 OneBot 实现
+	OneBotModeReverseWS = "reverse-ws" // 反向 WebSocket，由 OneBot 实现连接本程序
 )
 
+// notifyChannels 支持的推送渠道
+var notifyChannels = map[string]bool{
+	NotifyChannelOneBot: true,
+	NotifyChannelEmail:  true,
+}
+
+// smtpDefaultPorts 各加密方式的默认 SMTP 端口
+var smtpDefaultPorts = map[string]int{
+	SMTPSecurityStartTLS: 587,
+	SMTPSecuritySSL:      465,
+	SMTPSecurityNone:     25,
+}
+
+// EmailRecipient 邮件收件人，Courses 为空时接收全部课程
+type EmailRecipient struct {
+	Address string
+	Courses []string
+}
+
 // Config 保存监控程序的全部运行配置。
 type Config struct {
 	Username     string
 	Password     string
 	OneBotURL    string
 	OneBotToken  string
-	GroupList    []string
-	CourseList   []string
-	PollInterval int
-	OCRApiURL    string // 验证码识别 API 地址
+	OneBotMode   string // 通信方式: http / ws / reverse-ws，默认 http
+	OneBotListen string // 反向 WebSocket 监听地址，如 :6700
+	// OneBotImage 将课程变化绘制为图片推送，避免长消息被 QQ 折叠
+	OneBotImage     bool
+	OneBotImageFont string // 图片使用的字体文件，为空时使用内置字体
+
+	// OneBot 发送控制，避免超出 QQ 消息长度限制或触发风控
+	OneBotMaxLength       int   // 单条文本消息最大字符数，超出时按课程分段，0 表示不分段
+	OneBotRateLimit       int   // 全部群与私聊合计每分钟最多发送的消息数，0 表示不限
+	OneBotTargetRateLimit int   // 每个群或私聊每分钟最多发送的消息数，0 表示不限
+	OneBotMaxRetries      int   // 限流或临时失败时的最大重试次数
+	OneBotRetryRetCodes   []int // 视为限流或临时失败、需要重试的 retcode
+	GroupList             []string
+	PrivateList           []string // 接收私聊推送的 QQ 号
+	// CourseWatchers 关注各监控课程的 QQ 号，群消息中会 @ 这些成员。
+	// 键为 COURSE_LIST 中的课程号
+	CourseWatchers map[string][]string
+	// NotifyChannels 启用的推送渠道，默认仅 onebot
+	NotifyChannels []string
+	// 邮件推送，启用 email 渠道时使用
+	SMTPHost        string
+	SMTPPort        int // 为 0 时按加密方式使用 587 / 465 / 25
+	SMTPUsername    string
+	SMTPPassword    string
+	SMTPSecurity    string // starttls / ssl / none，默认 starttls
+	EmailFrom       string // 发件人地址，默认同 SMTP_USERNAME
+	EmailRecipients []EmailRecipient
+
+	// OutboxMaxAge 推送失败后重试的最长时间（分钟），超过后丢弃，0 表示不限
+	OutboxMaxAge int
+	// 推送消息模板文件，为空时使用内置模板
+	TemplateText     string // 纯文本渠道（OneBot）
+	TemplateMarkdown string // Markdown 渠道
+	TemplateHTML     string // HTML 渠道
+	CourseList       []string
+	ModuleList       []string // 搜索的选课模块，为空时使用轮次页面中开放的模块
+	PollInterval     int
+	Keepalive        int    // 持续轮询模式下的会话保活间隔（秒），0 表示关闭
+	OCRApiURL        string // 验证码识别 API 地址
+	DataDir          string // 运行时数据目录，session 与快照按账号存放在其子目录中
+
+	// OCR 协议适配，留空时使用 ddddocr API 的默认值
+	OCRMode         string        // 上传方式: base64 / file
+	OCREndpoint     string        // 接口路径
+	OCRImageField   string        // 图片字段路径或表单字段名
+	OCRTextField    string        // 响应中识别文本的字段路径
+	OCRSuccessField string        // 响应中成功标识的字段路径
+	OCRSuccessValue string        // 成功标识的期望值
+	OCRMessageField string        // 响应中错误信息的字段路径
+	OCRTimeout      time.Duration // 单次识别超时
+	OCRName         string        // 后端名称，用于数据集统计
+
+	CaptchaDatasetDir string // 验证码数据集目录，为空时不记录
+	CaptchaAlphabet   string // 验证码字符集，用于提交前校验识别结果
+	CaptchaLength     int    // 验证码长度
+	CaptchaMaxRefresh int    // 识别结果不合法时重新获取验证码的次数上限，0 表示不重新获取，-1 表示未设置
+
+	CaptchaPreprocess         string // 验证码预处理滤镜链，为空时不处理
+	CaptchaPreprocessDebugDir string // 预处理中间结果输出目录，为空时不输出
+
+	SessionKey string // session 文件加密密钥（32 字节随机数的 base64 或十六进制编码），来自 SESSION_KEY 或 SESSION_KEY_FILE
+
+	// 访问教务系统的网络配置
+	ProxyURL              string // HTTP / HTTPS / SOCKS5 代理地址
+	TLSCAFile             string // 额外信任的 CA 证书（PEM）
+	TLSServerName         string // TLS SNI 名称
+	TLSInsecureSkipVerify bool   // 跳过证书校验（仅用于调试）
+	UserAgent             string // 覆盖默认 User-Agent
+	MaxIdleConns          int    // 连接池最大空闲连接数
+	MaxIdleConnsPerHost   int    // 每个主机的最大空闲连接数
+	MaxConnsPerHost       int    // 每个主机的最大连接数，0 表示不限
+
+	// WebVPN 校外访问
+	WebVPNEnabled  bool
+	WebVPNURL      string // 网关地址
+	WebVPNUsername string // 门户账号，默认同教务账号
+	WebVPNPassword string // 门户密码，默认同教务密码
+	WebVPNTicket   string // 浏览器中已登录的 wengine_vpn_ticket，设置后跳过门户登录
+
+	// HAR 流量记录，用于排查教务系统页面或接口变化
+	HARDir      string // 输出目录，为空时不记录
+	HARMaxFiles int    // 保留的文件数上限
+	HARMaxMB    int    // 目录总大小上限（MB）
+
+	// 登录失败保护，跨进程生效
+	LoginMaxFailures   int // 统计窗口内允许的登录失败次数
+	LoginFailureWindow int // 统计窗口（分钟）
+
+	// QQ 机器人指令与订阅，需启用 onebot 渠道
+	BotEnabled          bool
+	BotMaxSubscriptions int      // 每个用户最多订阅的课程数
+	BotMaxTotal         int      // 全部用户最多订阅的总数
+	BotQueryCooldown    int      // 同一用户两次查询的间隔（秒），0 表示不限
+	BotGlobalCooldown   int      // 任意两次查询的间隔（秒），0 表示不限
+	OneBotEventListen   string   // http 模式下接收 OneBot 事件上报的监听地址
+	OneBotSecret        string   // 事件上报签名密钥，对应 OneBot 的 secret 配置
+	AdminList           []string // 可以通过 QQ 远程控制监控的管理员 QQ 号
 }
 
 // Load 从环境变量和 .env 文件加载配置并完成校验。
 func Load() (*Config, error) {
 	_ = godotenv.Load()
+	return parse()
+}
+
+// Reload 重新读取 .env 文件并完成校验，用于运行中重载配置。
+// 与 Load 不同，.env 中的值会覆盖进程中已有的同名环境变量。
+func Reload() (*Config, error) {
+	_ = godotenv.Overload()
+	return parse()
+}
 
+// SessionConfig 读写 session 文件所需的配置
+type SessionConfig struct {
+	Username   string
+	DataDir    string
+	SessionKey string // 同 Config.SessionKey
+}
+
+// LoadSession 只加载账号、数据目录与 session 密钥，供 session-export / session-import 使用，
+// 不要求也不校验通知、OCR 等与 session 文件无关的配置。
+func LoadSession() (*SessionConfig, error) {
+	_ = godotenv.Load()
+	cfg := &SessionConfig{
+		Username: strings.TrimSpace(os.Getenv("QFNU_USERNAME")),
+		DataDir:  envDataDir(),
+	}
+	if cfg.Username == "" {
+		return nil, fmt.Errorf("缺少必填配置项: QFNU_USERNAME")
+	}
+	key, err := envSessionKey()
+	if err != nil {
+		return nil, err
+	}
+	cfg.SessionKey = key
+	return cfg, nil
+}
+
+func parse() (*Config, error) {
 	cfg := &Config{
-		Username:     strings.TrimSpace(os.Getenv("QFNU_USERNAME")),
-		Password:     os.Getenv("QFNU_PASSWORD"),
-		OneBotURL:    strings.TrimRight(strings.TrimSpace(os.Getenv("ONEBOT_URL")), "/"),
-		OneBotToken:  strings.TrimSpace(os.Getenv("ONEBOT_TOKEN")),
-		GroupList:    splitAndTrim(os.Getenv("GROUP_LIST")),
-		CourseList:   splitAndTrim(os.Getenv("COURSE_LIST")),
-		PollInterval: DefaultPollInterval,
-		OCRApiURL:    strings.TrimRight(strings.TrimSpace(os.Getenv("OCR_API_URL")), "/"),
+		Username:        strings.TrimSpace(os.Getenv("QFNU_USERNAME")),
+		Password:        os.Getenv("QFNU_PASSWORD"),
+		OneBotURL:       strings.TrimRight(strings.TrimSpace(os.Getenv("ONEBOT_URL")), "/"),
+		OneBotToken:     strings.TrimSpace(os.Getenv("ONEBOT_TOKEN")),
+		OneBotMode:      strings.ToLower(strings.TrimSpace(os.Getenv("ONEBOT_MODE"))),
+		OneBotListen:    strings.TrimSpace(os.Getenv("ONEBOT_LISTEN")),
+		OneBotImage:     envBool("ONEBOT_IMAGE"),
+		OneBotImageFont: strings.TrimSpace(os.Getenv("ONEBOT_IMAGE_FONT")),
+
+		OneBotMaxLength:       envNonNegativeInt("ONEBOT_MAX_LENGTH", DefaultOneBotMaxLength),
+		OneBotRateLimit:       envNonNegativeInt("ONEBOT_RATE_LIMIT", DefaultOneBotRateLimit),
+		OneBotTargetRateLimit: envNonNegativeInt("ONEBOT_TARGET_RATE_LIMIT", DefaultOneBotTargetRateLimit),
+		OneBotMaxRetries:      envNonNegativeInt("ONEBOT_MAX_RETRIES", DefaultOneBotMaxRetries),
+		GroupList:             splitAndTrim(os.Getenv("GROUP_LIST")),
+		PrivateList:           splitAndTrim(os.Getenv("PRIVATE_LIST")),
+		NotifyChannels:        splitAndTrim(strings.ToLower(os.Getenv("NOTIFY_CHANNELS"))),
+		OutboxMaxAge:          envNonNegativeInt("OUTBOX_MAX_AGE", DefaultOutboxMaxAge),
+		SMTPHost:              strings.TrimSpace(os.Getenv("SMTP_HOST")),
+		SMTPPort:              envPositiveInt("SMTP_PORT", 0),
+		SMTPUsername:          strings.TrimSpace(os.Getenv("SMTP_USERNAME")),
+		SMTPPassword:          os.Getenv("SMTP_PASSWORD"),
+		SMTPSecurity:          strings.ToLower(strings.TrimSpace(os.Getenv("SMTP_SECURITY"))),
+		EmailFrom:             strings.TrimSpace(os.Getenv("EMAIL_FROM")),
+		TemplateText:          strings.TrimSpace(os.Getenv("TEMPLATE_TEXT")),
+		TemplateMarkdown:      strings.TrimSpace(os.Getenv("TEMPLATE_MARKDOWN")),
+		TemplateHTML:          strings.TrimSpace(os.Getenv("TEMPLATE_HTML")),
+		CourseList:            splitAndTrim(os.Getenv("COURSE_LIST")),
+		ModuleList:            splitAndTrim(os.Getenv("MODULE_LIST")),
+		PollInterval:          DefaultPollInterval,
+		Keepalive:             envNonNegativeInt("KEEPALIVE_INTERVAL", DefaultKeepalive),
+		OCRApiURL:             strings.TrimRight(strings.TrimSpace(os.Getenv("OCR_API_URL")), "/"),
+		DataDir:               envDataDir(),
+
+		OCRMode:         strings.ToLower(strings.TrimSpace(os.Getenv("OCR_MODE"))),
+		OCREndpoint:     strings.TrimSpace(os.Getenv("OCR_ENDPOINT")),
+		OCRImageField:   strings.TrimSpace(os.Getenv("OCR_IMAGE_FIELD")),
+		OCRTextField:    strings.TrimSpace(os.Getenv("OCR_TEXT_FIELD")),
+		OCRSuccessField: strings.TrimSpace(os.Getenv("OCR_SUCCESS_FIELD")),
+		OCRSuccessValue: strings.TrimSpace(os.Getenv("OCR_SUCCESS_VALUE")),
+		OCRMessageField: strings.TrimSpace(os.Getenv("OCR_MESSAGE_FIELD")),
+		OCRTimeout:      time.Duration(envPositiveInt("OCR_TIMEOUT", DefaultOCRTimeout)) * time.Second,
+		OCRName:         strings.TrimSpace(os.Getenv("OCR_NAME")),
+
+		CaptchaDatasetDir: strings.TrimSpace(os.Getenv("CAPTCHA_DATASET_DIR")),
+		CaptchaAlphabet:   strings.TrimSpace(os.Getenv("CAPTCHA_ALPHABET")),
+		CaptchaLength:     envPositiveInt("CAPTCHA_LENGTH", 0),
+		CaptchaMaxRefresh: envNonNegativeInt("CAPTCHA_MAX_REFRESH", -1),
+
+		CaptchaPreprocess:         strings.TrimSpace(os.Getenv("CAPTCHA_PREPROCESS")),
+		CaptchaPreprocessDebugDir: strings.TrimSpace(os.Getenv("CAPTCHA_PREPROCESS_DEBUG_DIR")),
+
+		ProxyURL:              strings.TrimSpace(os.Getenv("PROXY_URL")),
+		TLSCAFile:             strings.TrimSpace(os.Getenv("TLS_CA_FILE")),
+		TLSServerName:         strings.TrimSpace(os.Getenv("TLS_SERVER_NAME")),
+		TLSInsecureSkipVerify: envBool("TLS_INSECURE_SKIP_VERIFY"),
+		UserAgent:             strings.TrimSpace(os.Getenv("USER_AGENT")),
+		MaxIdleConns:          envPositiveInt("MAX_IDLE_CONNS", 0),
+		MaxIdleConnsPerHost:   envPositiveInt("MAX_IDLE_CONNS_PER_HOST", 0),
+		MaxConnsPerHost:       envPositiveInt("MAX_CONNS_PER_HOST", 0),
+
+		WebVPNEnabled:  envBool("WEBVPN_ENABLED"),
+		WebVPNURL:      strings.TrimSpace(os.Getenv("WEBVPN_URL")),
+		WebVPNUsername: strings.TrimSpace(os.Getenv("WEBVPN_USERNAME")),
+		WebVPNPassword: os.Getenv("WEBVPN_PASSWORD"),
+		WebVPNTicket:   strings.TrimSpace(os.Getenv("WEBVPN_TICKET")),
+
+		HARDir:      strings.TrimSpace(os.Getenv("HAR_DIR")),
+		HARMaxFiles: envPositiveInt("HAR_MAX_FILES", DefaultHARMaxFiles),
+		HARMaxMB:    envPositiveInt("HAR_MAX_MB", DefaultHARMaxMB),
+
+		LoginMaxFailures:   envPositiveInt("LOGIN_MAX_FAILURES", DefaultLoginMaxFailures),
+		LoginFailureWindow: envPositiveInt("LOGIN_FAILURE_WINDOW", DefaultLoginFailureWindow),
+
+		BotEnabled:          envBool("BOT_ENABLED"),
+		BotMaxSubscriptions: envPositiveInt("BOT_MAX_SUBSCRIPTIONS", DefaultBotMaxSubscriptions),
+		BotMaxTotal:         envPositiveInt("BOT_MAX_TOTAL_SUBSCRIPTIONS", DefaultBotMaxTotalSubscriptions),
+		BotQueryCooldown:    envNonNegativeInt("BOT_QUERY_COOLDOWN", DefaultBotQueryCooldown),
+		BotGlobalCooldown:   envNonNegativeInt("BOT_GLOBAL_QUERY_COOLDOWN", DefaultBotGlobalQueryCooldown),
+		OneBotEventListen:   strings.TrimSpace(os.Getenv("ONEBOT_EVENT_LISTEN")),
+		OneBotSecret:        strings.TrimSpace(os.Getenv("ONEBOT_SECRET")),
+		AdminList:           splitAndTrim(os.Getenv("ADMIN_LIST")),
 	}
 
 	if raw := strings.TrimSpace(os.Getenv("POLL_INTERVAL")); raw != "" {
@@ -49,6 +307,84 @@ func Load() (*Config, error) {
 		}
 	}
 
+	if cfg.WebVPNUsername == "" {
+		cfg.WebVPNUsername = cfg.Username
+	}
+	if cfg.WebVPNPassword == "" {
+		cfg.WebVPNPassword = cfg.Password
+	}
+
+	sessionKey, err := envSessionKey()
+	if err != nil {
+		return nil, err
+	}
+	cfg.SessionKey = sessionKey
+
+	if len(cfg.NotifyChannels) == 0 {
+		cfg.NotifyChannels = []string{NotifyChannelOneBot}
+	}
+	for _, channel := range cfg.NotifyChannels {
+		if !notifyChannels[channel] {
+			return nil, fmt.Errorf("NOTIFY_CHANNELS 包含不支持的渠道: %s", channel)
+		}
+	}
+
+	switch cfg.OneBotMode {
+	case "":
+		cfg.OneBotMode = OneBotModeHTTP
+	case OneBotModeHTTP, OneBotModeWS, OneBotModeReverseWS:
+	default:
+		return nil, fmt.Errorf("ONEBOT_MODE 仅支持 http、ws 或 reverse-ws: %s", cfg.OneBotMode)
+	}
+
+	retCodes := splitAndTrim(os.Getenv("ONEBOT_RETRY_RETCODES"))
+	if len(retCodes) == 0 {
+		retCodes = splitAndTrim(DefaultOneBotRetryRetCodes)
+	}
+	for _, raw := range retCodes {
+		code, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("ONEBOT_RETRY_RETCODES 格式错误: %s", raw)
+		}
+		cfg.OneBotRetryRetCodes = append(cfg.OneBotRetryRetCodes, code)
+	}
+
+	// 未启用 email 渠道时忽略邮件配置，残留的 SMTP_* 不影响启动
+	if cfg.HasNotifyChannel(NotifyChannelEmail) {
+		if cfg.SMTPSecurity == "" {
+			cfg.SMTPSecurity = SMTPSecurityStartTLS
+		}
+		defaultPort, ok := smtpDefaultPorts[cfg.SMTPSecurity]
+		if !ok {
+			return nil, fmt.Errorf("SMTP_SECURITY 仅支持 starttls、ssl 或 none: %s", cfg.SMTPSecurity)
+		}
+		if cfg.SMTPPort == 0 {
+			cfg.SMTPPort = defaultPort
+		}
+		if cfg.EmailFrom == "" {
+			cfg.EmailFrom = cfg.SMTPUsername
+		}
+		recipients, err := parseEmailRecipients(os.Getenv("EMAIL_TO"))
+		if err != nil {
+			return nil, err
+		}
+		cfg.EmailRecipients = recipients
+	}
+
+	watchers, err := parseCourseWatchers(os.Getenv("COURSE_WATCHERS"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.CourseWatchers = watchers
+
+	if cfg.CommandsEnabled() && !cfg.HasNotifyChannel(NotifyChannelOneBot) {
+		return nil, fmt.Errorf("BOT_ENABLED 与 ADMIN_LIST 需要在 NOTIFY_CHANNELS 中启用 onebot 渠道")
+	}
+
+	if cfg.OCRMode != "" && cfg.OCRMode != "base64" && cfg.OCRMode != "file" {
+		return nil, fmt.Errorf("OCR_MODE 仅支持 base64 或 file: %s", cfg.OCRMode)
+	}
+
 	var missing []string
 	if cfg.Username == "" {
 		missing = append(missing, "QFNU_USERNAME")
@@ -56,13 +392,43 @@ func Load() (*Config, error) {
 	if cfg.Password == "" {
 		missing = append(missing, "QFNU_PASSWORD")
 	}
-	if cfg.OneBotURL == "" {
-		missing = append(missing, "ONEBOT_URL")
+	if cfg.HasNotifyChannel(NotifyChannelOneBot) {
+		if cfg.OneBotMode == OneBotModeReverseWS {
+			if cfg.OneBotListen == "" {
+				missing = append(missing, "ONEBOT_LISTEN")
+			}
+		} else if cfg.OneBotURL == "" {
+			missing = append(missing, "ONEBOT_URL")
+		}
+		// 启用机器人时课程可以全部来自用户订阅
+		if !cfg.BotEnabled && len(cfg.GroupList) == 0 && len(cfg.PrivateList) == 0 {
+			missing = append(missing, "GROUP_LIST 或 PRIVATE_LIST")
+		}
+		if cfg.CommandsEnabled() && cfg.OneBotMode == OneBotModeHTTP && cfg.OneBotEventListen == "" {
+			missing = append(missing, "ONEBOT_EVENT_LISTEN")
+		}
+		// 指令通过监听端口接收事件，不校验来源时任何人都能伪造管理员指令
+		if cfg.CommandsEnabled() {
+			switch {
+			case cfg.OneBotMode == OneBotModeHTTP && cfg.OneBotSecret == "":
+				missing = append(missing, "ONEBOT_SECRET（启用指令时用于校验事件签名）")
+			case cfg.OneBotMode == OneBotModeReverseWS && cfg.OneBotToken == "":
+				missing = append(missing, "ONEBOT_TOKEN（启用指令时用于校验反向 WebSocket 连接）")
+			}
+		}
 	}
-	if len(cfg.GroupList) == 0 {
-		missing = append(missing, "GROUP_LIST")
+	if cfg.HasNotifyChannel(NotifyChannelEmail) {
+		if cfg.SMTPHost == "" {
+			missing = append(missing, "SMTP_HOST")
+		}
+		if cfg.EmailFrom == "" {
+			missing = append(missing, "EMAIL_FROM 或 SMTP_USERNAME")
+		}
+		if len(cfg.EmailRecipients) == 0 {
+			missing = append(missing, "EMAIL_TO")
+		}
 	}
-	if len(cfg.CourseList) == 0 {
+	if !cfg.BotEnabled && len(cfg.CourseList) == 0 {
 		missing = append(missing, "COURSE_LIST")
 	}
 	if cfg.OCRApiURL == "" {
@@ -75,6 +441,130 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// HasNotifyChannel 判断是否启用了指定推送渠道。
+func (c *Config) HasNotifyChannel(channel string) bool {
+	for _, ch := range c.NotifyChannels {
+		if ch == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandsEnabled 判断是否需要接收 QQ 消息处理指令（用户订阅或管理员远程控制）。
+func (c *Config) CommandsEnabled() bool {
+	return c.BotEnabled || len(c.AdminList) > 0
+}
+
+// envPositiveInt 读取正整数环境变量，缺失或非法时返回默认值。
+func envPositiveInt(key string, fallback int) int {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return fallback
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v <= 0 {
+		return fallback
+	}
+	return v
+}
+
+// envNonNegativeInt 读取非负整数环境变量，缺失或非法时返回默认值。
+func envNonNegativeInt(key string, fallback int) int {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return fallback
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return fallback
+	}
+	return v
+}
+
+// envBool 读取布尔环境变量，支持 1/true/yes/on（不区分大小写）。
+// envDataDir 读取 DATA_DIR，未配置时使用 DefaultDataDir
+func envDataDir() string {
+	if dir := strings.TrimSpace(os.Getenv("DATA_DIR")); dir != "" {
+		return dir
+	}
+	return DefaultDataDir
+}
+
+// envSessionKey 读取 SESSION_KEY，未配置时读取 SESSION_KEY_FILE 指向的文件
+func envSessionKey() (string, error) {
+	if key := strings.TrimSpace(os.Getenv("SESSION_KEY")); key != "" {
+		return key, nil
+	}
+	keyFile := strings.TrimSpace(os.Getenv("SESSION_KEY_FILE"))
+	if keyFile == "" {
+		return "", nil
+	}
+	content, err := os.ReadFile(keyFile)
+	if err != nil {
+		return "", fmt.Errorf("读取 SESSION_KEY_FILE 失败: %w", err)
+	}
+	key := strings.TrimSpace(string(content))
+	if key == "" {
+		return "", fmt.Errorf("SESSION_KEY_FILE 内容为空: %s", keyFile)
+	}
+	return key, nil
+}
+
+func envBool(key string) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(key))) {
+	case "1", "true", "yes", "on":
+		return true
+	default:
+		return false
+	}
+}
+
+// parseCourseWatchers 解析 COURSE_WATCHERS，格式为 课程号:QQ|QQ,课程号:QQ，
+// 同一课程号出现多次时合并。
+func parseCourseWatchers(raw string) (map[string][]string, error) {
+	watchers := make(map[string][]string)
+	for _, item := range splitAndTrim(raw) {
+		course, users, ok := strings.Cut(item, ":")
+		course = strings.TrimSpace(course)
+		if !ok || course == "" {
+			return nil, fmt.Errorf("COURSE_WATCHERS 格式错误[%s]: 应为 课程号:QQ|QQ", item)
+		}
+		for _, user := range strings.Split(users, "|") {
+			user = strings.TrimSpace(user)
+			if user == "" {
+				continue
+			}
+			if _, err := strconv.ParseInt(user, 10, 64); err != nil {
+				return nil, fmt.Errorf("COURSE_WATCHERS 中的 QQ 号格式错误[%s]", user)
+			}
+			watchers[course] = append(watchers[course], user)
+		}
+	}
+	return watchers, nil
+}
+
+// parseEmailRecipients 解析 EMAIL_TO，格式为 邮箱,邮箱:课程号|课程号，
+// 未指定课程号的收件人接收全部课程。
+func parseEmailRecipients(raw string) ([]EmailRecipient, error) {
+	var recipients []EmailRecipient
+	for _, item := range splitAndTrim(raw) {
+		address, courses, _ := strings.Cut(item, ":")
+		address = strings.TrimSpace(address)
+		if _, err := mail.ParseAddress(address); err != nil {
+			return nil, fmt.Errorf("EMAIL_TO 中的邮箱格式错误[%s]", address)
+		}
+		recipient := EmailRecipient{Address: address}
+		for _, course := range strings.Split(courses, "|") {
+			if course = strings.TrimSpace(course); course != "" {
+				recipient.Courses = append(recipient.Courses, course)
+			}
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
 func splitAndTrim(raw string) []string {
 	if strings.TrimSpace(raw) == "" {
 		return nil
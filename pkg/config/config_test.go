@@ -0,0 +1,163 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setEnv 设置测试用环境变量，值为空字符串表示清除
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for key, value := range env {
+		t.Setenv(key, value)
+		if value == "" {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestLoadSession(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "session.key")
+	if err := os.WriteFile(keyFile, []byte("file-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	emptyFile := filepath.Join(t.TempDir(), "empty.key")
+	if err := os.WriteFile(emptyFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    SessionConfig
+		wantErr string
+	}{
+		{
+			// 通知渠道等配置缺失或无效时也能加载
+			name: "only session settings",
+			env:  map[string]string{"QFNU_USERNAME": "2021001", "NOTIFY_CHANNELS": "pigeon", "SESSION_KEY": " env-key "},
+			want: SessionConfig{Username: "2021001", DataDir: DefaultDataDir, SessionKey: "env-key"},
+		},
+		{
+			name: "key file and data dir",
+			env:  map[string]string{"QFNU_USERNAME": "2021001", "DATA_DIR": "/var/lib/qxk", "SESSION_KEY_FILE": keyFile},
+			want: SessionConfig{Username: "2021001", DataDir: "/var/lib/qxk", SessionKey: "file-key"},
+		},
+		{
+			name: "SESSION_KEY wins over file",
+			env:  map[string]string{"QFNU_USERNAME": "2021001", "SESSION_KEY": "env-key", "SESSION_KEY_FILE": keyFile},
+			want: SessionConfig{Username: "2021001", DataDir: DefaultDataDir, SessionKey: "env-key"},
+		},
+		{
+			name:    "missing username",
+			env:     map[string]string{},
+			wantErr: "QFNU_USERNAME",
+		},
+		{
+			name:    "empty key file",
+			env:     map[string]string{"QFNU_USERNAME": "2021001", "SESSION_KEY_FILE": emptyFile},
+			wantErr: "SESSION_KEY_FILE 内容为空",
+		},
+		{
+			name:    "missing key file",
+			env:     map[string]string{"QFNU_USERNAME": "2021001", "SESSION_KEY_FILE": keyFile + ".missing"},
+			wantErr: "读取 SESSION_KEY_FILE 失败",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, map[string]string{"QFNU_USERNAME": "", "DATA_DIR": "", "SESSION_KEY": "", "SESSION_KEY_FILE": ""})
+			setEnv(t, tt.env)
+
+			got, err := LoadSession()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadSession() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadSession() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("LoadSession() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEmailSettings(t *testing.T) {
+	base := map[string]string{
+		"QFNU_USERNAME": "2021001",
+		"QFNU_PASSWORD": "secret",
+		"ONEBOT_URL":    "http://127.0.0.1:3000",
+		"GROUP_LIST":    "123456",
+		"COURSE_LIST":   "A001",
+		"OCR_API_URL":   "http://127.0.0.1:8000",
+	}
+	tests := []struct {
+		name     string
+		env      map[string]string
+		wantPort int
+		wantFrom string
+		wantErr  string
+	}{
+		{
+			name: "email disabled ignores invalid settings",
+			env:  map[string]string{"NOTIFY_CHANNELS": "onebot", "SMTP_SECURITY": "tls", "EMAIL_TO": "not-an-address"},
+		},
+		{
+			name:     "email defaults",
+			env:      map[string]string{"NOTIFY_CHANNELS": "onebot,email", "SMTP_HOST": "smtp.example.com", "SMTP_USERNAME": "bot@example.com", "EMAIL_TO": "a@example.com"},
+			wantPort: 587,
+			wantFrom: "bot@example.com",
+		},
+		{
+			name:     "ssl port",
+			env:      map[string]string{"NOTIFY_CHANNELS": "email", "SMTP_HOST": "smtp.example.com", "SMTP_SECURITY": "SSL", "EMAIL_FROM": "bot@example.com", "EMAIL_TO": "a@example.com"},
+			wantPort: 465,
+			wantFrom: "bot@example.com",
+		},
+		{
+			name:    "invalid security",
+			env:     map[string]string{"NOTIFY_CHANNELS": "email", "SMTP_HOST": "smtp.example.com", "SMTP_SECURITY": "tls", "EMAIL_FROM": "bot@example.com", "EMAIL_TO": "a@example.com"},
+			wantErr: "SMTP_SECURITY",
+		},
+		{
+			name:    "invalid recipient",
+			env:     map[string]string{"NOTIFY_CHANNELS": "email", "SMTP_HOST": "smtp.example.com", "EMAIL_FROM": "bot@example.com", "EMAIL_TO": "not-an-address"},
+			wantErr: "EMAIL_TO",
+		},
+		{
+			name:    "missing smtp host",
+			env:     map[string]string{"NOTIFY_CHANNELS": "email", "EMAIL_FROM": "bot@example.com", "EMAIL_TO": "a@example.com"},
+			wantErr: "SMTP_HOST",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, map[string]string{
+				"NOTIFY_CHANNELS": "", "SMTP_HOST": "", "SMTP_PORT": "", "SMTP_USERNAME": "", "SMTP_SECURITY": "",
+				"EMAIL_FROM": "", "EMAIL_TO": "", "SESSION_KEY": "", "SESSION_KEY_FILE": "", "BOT_ENABLED": "", "ADMIN_LIST": "",
+			})
+			setEnv(t, base)
+			setEnv(t, tt.env)
+
+			cfg, err := parse()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parse() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if cfg.SMTPPort != tt.wantPort || cfg.EmailFrom != tt.wantFrom {
+				t.Errorf("SMTPPort = %d, EmailFrom = %q, want %d, %q", cfg.SMTPPort, cfg.EmailFrom, tt.wantPort, tt.wantFrom)
+			}
+		})
+	}
+}
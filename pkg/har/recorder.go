@@ -0,0 +1,375 @@
+package har
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxFiles     = 20
+	DefaultMaxBytes     = 100 << 20
+	DefaultMaxEntries   = 500
+	DefaultMaxBodyBytes = 1 << 20
+
+	fileExt     = ".har"
+	fileTrailer = "\n]}}\n"
+)
+
+// Config HAR 记录配置
+type Config struct {
+	Dir          string // 输出目录
+	MaxFiles     int    // 保留的 HAR 文件数上限
+	MaxBytes     int64  // 目录内 HAR 文件总大小上限
+	MaxEntries   int    // 单个文件的条目数上限，超过后切换新文件
+	MaxBodyBytes int    // 单个请求 / 响应体记录的字节数上限，超出部分截断
+}
+
+// Recorder 将经过的 HTTP 请求与响应写入 HAR 文件。
+// 密码、encoded、验证码文本、Cookie 与重定向中的 ticket 等敏感值在写入前脱敏。
+//
+// 文件在每条记录写入后都保持为合法的 HAR（末尾随时补齐结束符），
+// 即使进程被 log.Fatalf 中断也能直接用浏览器开发者工具打开。
+type Recorder struct {
+	cfg Config
+
+	mu      sync.Mutex
+	file    *os.File
+	path    string
+	entries int
+}
+
+// New 创建 HAR 记录器，需通过 Wrap 接入 HTTP 客户端的传输层。
+func New(cfg Config) (*Recorder, error) {
+	cfg.Dir = strings.TrimSpace(cfg.Dir)
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("HAR 输出目录不能为空")
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = DefaultMaxFiles
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultMaxEntries
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("创建 HAR 目录失败: %w", err)
+	}
+	return &Recorder{cfg: cfg}, nil
+}
+
+// Dir 返回 HAR 输出目录
+func (r *Recorder) Dir() string {
+	return r.cfg.Dir
+}
+
+// Wrap 返回记录经过流量的传输层，可直接传给 cas.WithTransportWrapper
+func (r *Recorder) Wrap(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, recorder: r}
+}
+
+// Close 关闭当前 HAR 文件
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closeFile()
+}
+
+type transport struct {
+	base     http.RoundTripper
+	recorder *Recorder
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+
+	reqBody, req, err := t.recorder.captureRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := Entry{
+		StartedDateTime: start.Format(time.RFC3339Nano),
+		Request:         t.recorder.buildRequest(req, reqBody),
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	waited := time.Since(start)
+	if err != nil {
+		entry.Time = millis(waited)
+		entry.Timings = Timings{Wait: millis(waited)}
+		entry.Error = err.Error()
+		entry.Response = Response{Cookies: []Cookie{}, Headers: []NameValue{}, HeadersSize: -1, BodySize: -1}
+		t.recorder.add(entry)
+		return nil, err
+	}
+
+	body, readErr := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if readErr != nil {
+		resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), errReader{readErr}))
+		entry.Error = readErr.Error()
+	} else {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	total := time.Since(start)
+	entry.Time = millis(total)
+	entry.Timings = Timings{Wait: millis(waited), Receive: millis(total - waited)}
+	entry.Response = t.recorder.buildResponse(resp, body)
+	t.recorder.add(entry)
+	return resp, nil
+}
+
+// captureRequestBody 读取请求体副本。优先使用 GetBody，避免消耗原始请求体；
+// 否则读出后以新的请求体克隆请求，保证下游仍可重放。
+func (r *Recorder) captureRequestBody(req *http.Request) ([]byte, *http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, req, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err == nil {
+			defer rc.Close()
+			body, err := io.ReadAll(rc)
+			if err == nil {
+				return body, req, nil
+			}
+		}
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取请求体失败: %w", err)
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, clone, nil
+}
+
+func (r *Recorder) buildRequest(req *http.Request, body []byte) Request {
+	harReq := Request{
+		Method:      req.Method,
+		URL:         redactURL(req.URL),
+		HTTPVersion: httpVersion(req.Proto),
+		Cookies:     cookieList(req.Cookies()),
+		Headers:     headerList(req.Header),
+		QueryString: queryString(req.URL),
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	if body != nil {
+		mimeType := req.Header.Get("Content-Type")
+		text, params := redactBody(mimeType, body)
+		harReq.PostData = &PostData{
+			MimeType: mimeType,
+			Params:   params,
+			Text:     truncate(text, r.cfg.MaxBodyBytes),
+		}
+	}
+	return harReq
+}
+
+func (r *Recorder) buildResponse(resp *http.Response, body []byte) Response {
+	mimeType := resp.Header.Get("Content-Type")
+	content := Content{Size: len(body), MimeType: mimeType}
+
+	data := body
+	if len(data) > r.cfg.MaxBodyBytes {
+		data = data[:r.cfg.MaxBodyBytes]
+		content.Comment = fmt.Sprintf("已截断，仅保留前 %d 字节", r.cfg.MaxBodyBytes)
+	}
+	if isTextMime(mimeType) {
+		content.Text = string(data)
+	} else if len(data) > 0 {
+		content.Text = base64.StdEncoding.EncodeToString(data)
+		content.Encoding = "base64"
+	}
+
+	return Response{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: httpVersion(resp.Proto),
+		Cookies:     cookieList(resp.Cookies()),
+		Headers:     headerList(resp.Header),
+		Content:     content,
+		RedirectURL: redactURLString(resp.Header.Get("Location")),
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+}
+
+// add 追加一条记录，写入失败只记录日志，不影响业务请求
+func (r *Recorder) add(entry Entry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("[WARN] 序列化 HAR 记录失败: %v", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file != nil && r.entries >= r.cfg.MaxEntries {
+		if err := r.closeFile(); err != nil {
+			log.Printf("[WARN] 关闭 HAR 文件失败: %v", err)
+		}
+	}
+	if r.file == nil {
+		if err := r.openFile(); err != nil {
+			log.Printf("[WARN] 创建 HAR 文件失败: %v", err)
+			return
+		}
+	}
+
+	// 覆盖上一次写入的结束符，再补上新的结束符，保证文件始终完整
+	if _, err := r.file.Seek(-int64(len(fileTrailer)), io.SeekEnd); err != nil {
+		log.Printf("[WARN] 写入 HAR 文件失败: %v", err)
+		return
+	}
+	var buf bytes.Buffer
+	if r.entries > 0 {
+		buf.WriteString(",")
+	}
+	buf.WriteString("\n")
+	buf.Write(data)
+	buf.WriteString(fileTrailer)
+	if _, err := r.file.Write(buf.Bytes()); err != nil {
+		log.Printf("[WARN] 写入 HAR 文件失败: %v", err)
+		return
+	}
+	r.entries++
+}
+
+func (r *Recorder) openFile() error {
+	name := time.Now().Format("20060102_150405.000000") + fileExt
+	path := filepath.Join(r.cfg.Dir, name)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+
+	creator, _ := json.Marshal(Creator{Name: "easy-qfnu-xk-monitor", Version: "1.0"})
+	header := `{"log":{"version":"1.2","creator":` + string(creator) + `,"entries":[`
+	if _, err := f.WriteString(header + fileTrailer); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	r.file = f
+	r.path = path
+	r.entries = 0
+	log.Printf("[DEBUG] HAR 记录写入: %s", path)
+
+	r.prune()
+	return nil
+}
+
+func (r *Recorder) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// prune 按文件名（即创建时间）从旧到新删除超出数量或总大小上限的 HAR 文件，当前文件除外
+func (r *Recorder) prune() {
+	matches, err := filepath.Glob(filepath.Join(r.cfg.Dir, "*"+fileExt))
+	if err != nil {
+		return
+	}
+	sort.Strings(matches)
+
+	type harFile struct {
+		path string
+		size int64
+	}
+	files := make([]harFile, 0, len(matches))
+	var total int64
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		files = append(files, harFile{path: path, size: info.Size()})
+		total += info.Size()
+	}
+
+	for len(files) > 0 && (len(files) > r.cfg.MaxFiles || total > r.cfg.MaxBytes) {
+		oldest := files[0]
+		files = files[1:]
+		if oldest.path == r.path {
+			continue
+		}
+		if err := os.Remove(oldest.path); err != nil {
+			log.Printf("[WARN] 清理 HAR 文件失败: %v", err)
+			continue
+		}
+		total -= oldest.size
+		log.Printf("[DEBUG] 已清理旧 HAR 文件: %s", oldest.path)
+	}
+}
+
+func isTextMime(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		mediaType = strings.ToLower(mimeType)
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.Contains(mediaType, "json"),
+		strings.Contains(mediaType, "javascript"),
+		strings.Contains(mediaType, "xml"),
+		mediaType == "application/x-www-form-urlencoded":
+		return true
+	default:
+		return false
+	}
+}
+
+func httpVersion(proto string) string {
+	if proto == "" {
+		return "HTTP/1.1"
+	}
+	return proto
+}
+
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	return s[:limit]
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) { return 0, e.err }
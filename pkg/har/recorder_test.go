@@ -0,0 +1,90 @@
+package har
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorderRedactsLoginFlow(t *testing.T) {
+	const (
+		encoded    = "MjAyMTAwMQ==%%%c2VjcmV0UGFzcw=="
+		randomCode = "x7k2"
+		sessionID  = "JSESSIONID-secret-value"
+		ticket     = "ST-4242-secret-ticket-cas"
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: sessionID, Path: "/"})
+		http.Redirect(w, r, "http://zhjw.qfnu.edu.cn/sso.jsp?ticket="+ticket, http.StatusFound)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	recorder, err := New(Config{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{
+		Transport: recorder.Wrap(nil),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	form := url.Values{"userAccount": {"2021001"}, "encoded": {encoded}, "RANDOMCODE": {randomCode}}
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/jsxsd/xk/LoginToXk?ticket="+ticket, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "JSESSIONID", Value: sessionID})
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+	if location := resp.Header.Get("Location"); !strings.Contains(location, ticket) {
+		t.Errorf("业务侧收到的 Location 被改写: %s", location)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+fileExt))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("HAR files = %v (%v), want 1", files, err)
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file File
+	if err := json.Unmarshal(content, &file); err != nil {
+		t.Fatalf("HAR 文件不是合法 JSON: %v", err)
+	}
+	if len(file.Log.Entries) != 1 {
+		t.Fatalf("entries = %d, want 1", len(file.Log.Entries))
+	}
+
+	for _, secret := range []string{encoded, url.QueryEscape(encoded), randomCode, sessionID, ticket} {
+		if strings.Contains(string(content), secret) {
+			t.Errorf("HAR 文件包含敏感值 %q", secret)
+		}
+	}
+	entry := file.Log.Entries[0]
+	if want := "http://zhjw.qfnu.edu.cn/sso.jsp?ticket=" + url.QueryEscape("***(25)"); entry.Response.RedirectURL != want {
+		t.Errorf("redirectURL = %s, want %s", entry.Response.RedirectURL, want)
+	}
+	if len(entry.Response.Cookies) != 1 || entry.Response.Cookies[0].Value != "***(23)" {
+		t.Errorf("response cookies = %v", entry.Response.Cookies)
+	}
+	if !strings.Contains(entry.Request.PostData.Text, "userAccount=2021001") {
+		t.Errorf("非敏感参数不应脱敏: %s", entry.Request.PostData.Text)
+	}
+}
@@ -0,0 +1,156 @@
+package har
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/logger"
+)
+
+// sensitiveParams 需要脱敏的表单 / 查询参数与 JSON 字段（小写比较）
+var sensitiveParams = map[string]bool{
+	"userpassword": true, // 教务登录密码（当前为空，以防后续启用）
+	"encoded":      true, // 账号密码的编码串
+	"randomcode":   true, // 验证码文本
+	"password":     true, // WebVPN 门户密码
+	"pwd":          true,
+	"ticket":       true,
+	"token":        true,
+}
+
+// sensitiveHeaders 需要脱敏的请求 / 响应头（小写比较）
+var sensitiveHeaders = map[string]bool{
+	"cookie":              true,
+	"set-cookie":          true,
+	"authorization":       true,
+	"proxy-authorization": true,
+}
+
+// urlHeaders 值为 URL 的请求 / 响应头（小写比较），只脱敏其中的敏感查询参数，
+// 例如 CAS 重定向 Location 中的 ticket
+var urlHeaders = map[string]bool{
+	"location": true,
+	"referer":  true,
+}
+
+func isSensitiveParam(name string) bool {
+	return sensitiveParams[strings.ToLower(name)]
+}
+
+// redactURLString 脱敏 URL 字符串中的敏感参数，无法解析时整体脱敏
+func redactURLString(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return logger.Redact(raw)
+	}
+	return redactURL(u)
+}
+
+// redactURL 脱敏 URL 查询串中的敏感参数
+func redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	clone := *u
+	clone.RawQuery = redactForm(u.Query()).Encode()
+	return clone.String()
+}
+
+func redactForm(values url.Values) url.Values {
+	out := make(url.Values, len(values))
+	for key, list := range values {
+		for _, v := range list {
+			if isSensitiveParam(key) {
+				v = logger.Redact(v)
+			}
+			out.Add(key, v)
+		}
+	}
+	return out
+}
+
+func queryString(u *url.URL) []NameValue {
+	return formParams(u.Query())
+}
+
+func formParams(values url.Values) []NameValue {
+	params := make([]NameValue, 0, len(values))
+	for key, list := range redactForm(values) {
+		for _, v := range list {
+			params = append(params, NameValue{Name: key, Value: v})
+		}
+	}
+	return params
+}
+
+func headerList(h http.Header) []NameValue {
+	headers := make([]NameValue, 0, len(h))
+	for name, list := range h {
+		lower := strings.ToLower(name)
+		for _, v := range list {
+			switch {
+			case sensitiveHeaders[lower]:
+				v = logger.Redact(v)
+			case urlHeaders[lower]:
+				v = redactURLString(v)
+			}
+			headers = append(headers, NameValue{Name: name, Value: v})
+		}
+	}
+	return headers
+}
+
+func cookieList(cookies []*http.Cookie) []Cookie {
+	list := make([]Cookie, 0, len(cookies))
+	for _, c := range cookies {
+		list = append(list, Cookie{Name: c.Name, Value: logger.Redact(c.Value)})
+	}
+	return list
+}
+
+// redactBody 按内容类型脱敏请求体，返回脱敏后的文本与表单参数
+func redactBody(mimeType string, body []byte) (string, []NameValue) {
+	switch {
+	case strings.HasPrefix(mimeType, "application/x-www-form-urlencoded"):
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "", nil
+		}
+		return redactForm(values).Encode(), formParams(values)
+	case strings.Contains(mimeType, "json"):
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			return string(body), nil
+		}
+		data, err := json.Marshal(redactJSON(v))
+		if err != nil {
+			return "", nil
+		}
+		return string(data), nil
+	default:
+		return string(body), nil
+	}
+}
+
+func redactJSON(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for key, value := range t {
+			if s, ok := value.(string); ok && isSensitiveParam(key) {
+				t[key] = logger.Redact(s)
+				continue
+			}
+			t[key] = redactJSON(value)
+		}
+	case []any:
+		for i := range t {
+			t[i] = redactJSON(t[i])
+		}
+	}
+	return v
+}
@@ -0,0 +1,110 @@
+package har
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		mimeType   string
+		body       string
+		wantText   string
+		wantParams []NameValue
+	}{
+		{
+			name:     "login form",
+			mimeType: "application/x-www-form-urlencoded; charset=UTF-8",
+			body:     "userAccount=2021001&encoded=MjAyMTAwMQ%3D%3D%25%25%25c2VjcmV0&RANDOMCODE=ab12",
+			wantText: "RANDOMCODE=%2A%2A%2A%284%29&encoded=%2A%2A%2A%2823%29&userAccount=2021001",
+			wantParams: []NameValue{
+				{Name: "RANDOMCODE", Value: "***(4)"},
+				{Name: "encoded", Value: "***(23)"},
+				{Name: "userAccount", Value: "2021001"},
+			},
+		},
+		{
+			name:     "nested json",
+			mimeType: "application/json",
+			body:     `{"user": {"username": "u", "password": "secret"}, "list": [{"token": "abc"}]}`,
+			wantText: `{"list":[{"token":"***(3)"}],"user":{"password":"***(6)","username":"u"}}`,
+		},
+		{
+			name:     "invalid json is kept",
+			mimeType: "application/json",
+			body:     `{"password":`,
+			wantText: `{"password":`,
+		},
+		{
+			name:     "plain text is kept",
+			mimeType: "text/plain",
+			body:     "hello",
+			wantText: "hello",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, params := redactBody(tt.mimeType, []byte(tt.body))
+			if text != tt.wantText {
+				t.Errorf("text = %s, want %s", text, tt.wantText)
+			}
+			slices.SortFunc(params, func(a, b NameValue) int { return strings.Compare(a.Name, b.Name) })
+			if !slices.Equal(params, tt.wantParams) {
+				t.Errorf("params = %v, want %v", params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestRedactURLString(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"", ""},
+		{"http://zhjw.qfnu.edu.cn/jsxsd/", "http://zhjw.qfnu.edu.cn/jsxsd/"},
+		{
+			"http://zhjw.qfnu.edu.cn/sso.jsp?ticket=ST-123-abc-cas&lang=zh",
+			"http://zhjw.qfnu.edu.cn/sso.jsp?lang=zh&ticket=%2A%2A%2A%2814%29",
+		},
+		{"/jsxsd/framework/xsMain.jsp?Ticket=ST-1", "/jsxsd/framework/xsMain.jsp?Ticket=%2A%2A%2A%284%29"},
+		{"http://[::1:bad/?ticket=ST-1", "***(28)"},
+	}
+	for _, tt := range tests {
+		if got := redactURLString(tt.raw); got != tt.want {
+			t.Errorf("redactURLString(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestHeaderList(t *testing.T) {
+	h := http.Header{
+		"Cookie":        {"JSESSIONID=abc"},
+		"Set-Cookie":    {"JSESSIONID=def; Path=/"},
+		"Authorization": {"Bearer token"},
+		"Location":      {"http://zhjw.qfnu.edu.cn/sso.jsp?ticket=ST-1"},
+		"Referer":       {"http://ids.qfnu.edu.cn/login?service=x"},
+		"Content-Type":  {"text/html"},
+	}
+	want := map[string]string{
+		"Cookie":        "***(14)",
+		"Set-Cookie":    "***(22)",
+		"Authorization": "***(12)",
+		"Location":      "http://zhjw.qfnu.edu.cn/sso.jsp?ticket=" + url.QueryEscape("***(4)"),
+		"Referer":       "http://ids.qfnu.edu.cn/login?service=x",
+		"Content-Type":  "text/html",
+	}
+	got := headerList(h)
+	if len(got) != len(want) {
+		t.Fatalf("headerList() = %v", got)
+	}
+	for _, header := range got {
+		if header.Value != want[header.Name] {
+			t.Errorf("%s = %q, want %q", header.Name, header.Value, want[header.Name])
+		}
+	}
+}
@@ -1,12 +1,18 @@
 package jwxt
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"html"
 	"io"
+	"log"
+	"maps"
 	"net/http"
 	"net/url"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
@@ -20,7 +26,37 @@ const (
 	roundQueryParam = "jx0502zbid"
 )
 
-var ErrSelectionRoundNotFound = errors.New("未找到可进入的选课轮次")
+var (
+	ErrSelectionRoundNotFound = errors.New("未找到可进入的选课轮次")
+
+	// ErrRoundNotStarted 表示轮次尚未到选课时间。
+	ErrRoundNotStarted = errors.New("未到选课时间")
+	// ErrRoundEnded 表示轮次选课已结束。
+	ErrRoundEnded = errors.New("选课已结束")
+	// ErrNotInRoundList 表示当前学生不在该轮次的选课名单中。
+	ErrNotInRoundList = errors.New("不在选课名单中")
+	// ErrRoundNotEntered 表示选课首页既没有提示信息也没有选课模块，无法确认已进入轮次。
+	ErrRoundNotEntered = errors.New("未能确认已进入选课轮次")
+
+	// roundStateRules 选课首页提示信息与错误类型的对应关系，按顺序匹配
+	roundStateRules = []struct {
+		err      error
+		keywords []string
+	}{
+		{ErrNotInRoundList, []string{"不在选课名单", "不在本轮选课名单", "不在选课范围", "没有选课资格", "无选课资格"}},
+		{ErrRoundNotStarted, []string{"未到选课时间", "选课尚未开始", "选课未开始", "还未开始"}},
+		{ErrRoundEnded, []string{"选课已结束", "选课时间已过", "已经结束", "选课已截止"}},
+	}
+
+	moduleEntryPattern = regexp.MustCompile(`comeIn([A-Z][A-Za-z]+)`)
+	alertPattern       = regexp.MustCompile(`alert\(\s*["']([^"']+)["']`)
+)
+
+// RoundEntry 表示成功进入的选课轮次及页面实际提供的选课模块。
+type RoundEntry struct {
+	RoundID string
+	Modules []string // 选课模块标识，如 xsxkBxqjhxk，按 ModuleTypes 顺序排列
+}
 
 // SelectionRound 表示页面中的一个选课轮次。
 type SelectionRound struct {
@@ -111,28 +147,123 @@ func IsSelectionRoundNotFound(err error) bool {
 }
 
 // EnterSelectionRound 进入指定轮次并激活选课上下文。
-func EnterSelectionRound(ctx context.Context, client *http.Client, roundID string) error {
+//
+// 进入后解析选课首页:
+//   - 从页面中的 comeInXxx 入口提取本轮开放的选课模块，找到模块即进入成功
+//   - 没有模块时，页面提示“未到选课时间”“选课已结束”“不在选课名单”则返回对应的错误
+//   - 返回登录页时返回 ErrSessionExpired
+//   - 既无提示也无选课模块时返回 ErrRoundNotEntered
+func EnterSelectionRound(ctx context.Context, client *http.Client, roundID string) (*RoundEntry, error) {
 	roundID = strings.TrimSpace(roundID)
 	if roundID == "" {
-		return fmt.Errorf("roundID 不能为空")
+		return nil, fmt.Errorf("roundID 不能为空")
 	}
 
 	viewURL, err := buildRoundURL(RoundViewPath, roundID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	indexURL, err := buildRoundURL(RoundIndexPath, roundID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// 先访问页面入口，再访问 index 激活会话。
-	_ = tryEnterRound(ctx, client, viewURL)
+	if _, err := fetchRoundPage(ctx, client, viewURL); err != nil {
+		return nil, fmt.Errorf("访问轮次入口失败: %w", err)
+	}
+
+	body, err := fetchRoundPage(ctx, client, indexURL)
+	if err != nil {
+		return nil, fmt.Errorf("进入轮次失败: %w", err)
+	}
+	return parseRoundIndex(roundID, body)
+}
+
+// IsRoundUnavailable 判断错误是否表示轮次当前不可选（未开始、已结束或不在名单中）。
+func IsRoundUnavailable(err error) bool {
+	return errors.Is(err, ErrRoundNotStarted) ||
+		errors.Is(err, ErrRoundEnded) ||
+		errors.Is(err, ErrNotInRoundList)
+}
+
+// parseRoundIndex 解析选课首页，提取开放的选课模块并识别轮次状态。
+// 找到选课模块即视为已进入轮次；只有没有模块时才按页面提示判断轮次状态，
+// 且只匹配可见文本与 alert 提示，避免脚本或隐藏模板中的文字造成误判。
+func parseRoundIndex(roundID string, body []byte) (*RoundEntry, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("解析选课首页失败: %w", err)
+	}
+
+	offered := make(map[string]struct{})
+	doc.Find("a, li, button, input, div, span").Each(func(_ int, sel *goquery.Selection) {
+		for _, attr := range []string{"href", "onclick", "src"} {
+			value, ok := sel.Attr(attr)
+			if !ok {
+				continue
+			}
+			for _, match := range moduleEntryPattern.FindAllStringSubmatch(value, -1) {
+				offered["xsxk"+match[1]] = struct{}{}
+			}
+		}
+	})
+
+	entry := &RoundEntry{RoundID: roundID}
+	for _, module := range ModuleTypes {
+		if _, ok := offered[module]; ok {
+			entry.Modules = append(entry.Modules, module)
+			delete(offered, module)
+		}
+	}
+	if len(offered) > 0 {
+		// 未知模块的查询接口与参数无法确认，只记录日志，便于发现教务系统新增的选课方式
+		unknown := slices.Sorted(maps.Keys(offered))
+		log.Printf("[WARN] 选课首页包含未支持的选课模块，已忽略: %s", strings.Join(unknown, ", "))
+	}
+	if len(entry.Modules) > 0 {
+		return entry, nil
+	}
 
-	if err := tryEnterRound(ctx, client, indexURL); err != nil {
-		return fmt.Errorf("进入轮次失败: %w", err)
+	messages := pageMessages(doc)
+	for _, rule := range roundStateRules {
+		for _, keyword := range rule.keywords {
+			for _, message := range messages {
+				if strings.Contains(message, keyword) {
+					return nil, fmt.Errorf("%w: %s", rule.err, message)
+				}
+			}
+		}
+	}
+
+	if looksLikeLoginHTML(body) && strings.Contains(html.UnescapeString(string(body)), "登录") {
+		return nil, fmt.Errorf("%w: 选课首页返回登录页", ErrSessionExpired)
 	}
-	return nil
+	return nil, fmt.Errorf("%w: 页面中未找到选课模块入口", ErrRoundNotEntered)
+}
+
+// pageMessages 返回页面中用户可见的提示：脚本中 alert 弹出的文字，
+// 以及去除 script、style 与隐藏元素后的各段文本
+func pageMessages(doc *goquery.Document) []string {
+	var messages []string
+	doc.Find("script").Each(func(_ int, sel *goquery.Selection) {
+		for _, match := range alertPattern.FindAllStringSubmatch(sel.Text(), -1) {
+			if message := normalizeSpace(match[1]); message != "" {
+				messages = append(messages, message)
+			}
+		}
+	})
+
+	doc.Find("script, style, noscript, template, [hidden], [style*='display:none'], [style*='display: none']").Remove()
+	doc.Find("body *").AddBack().Contents().Each(func(_ int, sel *goquery.Selection) {
+		if goquery.NodeName(sel) != "#text" {
+			return
+		}
+		if message := normalizeSpace(sel.Text()); message != "" {
+			messages = append(messages, message)
+		}
+	})
+	return messages
 }
 
 func fetchRoundDocument(ctx context.Context, client *http.Client) (*goquery.Document, error) {
@@ -185,23 +316,29 @@ func extractRoundIDFromHref(href string) string {
 	return ""
 }
 
-func tryEnterRound(ctx context.Context, client *http.Client, rawURL string) error {
+// fetchRoundPage 请求轮次相关页面并返回页面内容
+func fetchRoundPage(ctx context.Context, client *http.Client, rawURL string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
 	if err != nil {
-		return fmt.Errorf("创建进入轮次请求失败: %w", err)
+		return nil, fmt.Errorf("创建进入轮次请求失败: %w", err)
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("进入轮次请求失败: %w", err)
+		return nil, fmt.Errorf("进入轮次请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return fmt.Errorf("进入轮次响应异常: %d, body=%q", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("进入轮次响应异常: %d, body=%q", resp.StatusCode, string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取轮次页面失败: %w", err)
 	}
-	return nil
+	return body, nil
 }
 
 func buildRoundURL(path string, roundID string) (string, error) {
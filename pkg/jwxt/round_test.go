@@ -0,0 +1,88 @@
+package jwxt
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// roundIndexPage 模拟选课首页，body 为页面主体
+func roundIndexPage(body string) []byte {
+	return []byte(`<!DOCTYPE html><html><head><title>学生选课</title>
+<script type="text/javascript">
+	function comeInXsxkTemplate() { return "hidden template"; }
+</script>
+</head><body>` + body + `</body></html>`)
+}
+
+func TestParseRoundIndex(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		wantModules []string
+		wantErr     error
+	}{
+		{
+			name: "modules in ModuleTypes order",
+			body: roundIndexPage(`
+				<li onclick="comeInXxxk()">选修选课</li>
+				<a href="javascript:comeInBxqjhxk();">本学期计划选课</a>
+				<button onclick="comeInKnjxk()">专业内跨年级选课</button>`),
+			wantModules: []string{"xsxkKnjxk", "xsxkBxqjhxk", "xsxkXxxk"},
+		},
+		{
+			name: "unknown module is ignored",
+			body: roundIndexPage(`
+				<li onclick="comeInGgxxkxk()">公选课选课</li>
+				<li onclick="comeInTyxk()">体育选课</li>`),
+			wantModules: []string{"xsxkGgxxkxk"},
+		},
+		{
+			name:    "only unknown modules",
+			body:    roundIndexPage(`<li onclick="comeInTyxk()">体育选课</li>`),
+			wantErr: ErrRoundNotEntered,
+		},
+		{
+			name:    "no modules and no message",
+			body:    roundIndexPage(`<div>欢迎使用选课系统</div>`),
+			wantErr: ErrRoundNotEntered,
+		},
+		{
+			name:    "not started alert",
+			body:    roundIndexPage(`<script>alert("未到选课时间，请耐心等待");</script>`),
+			wantErr: ErrRoundNotStarted,
+		},
+		{
+			name:    "hidden template text is ignored",
+			body:    roundIndexPage(`<div style="display:none">选课已结束</div><div>暂无公告</div>`),
+			wantErr: ErrRoundNotEntered,
+		},
+		{
+			name:    "not in round list",
+			body:    roundIndexPage(`<div class="tips">您不在本轮选课名单中</div>`),
+			wantErr: ErrNotInRoundList,
+		},
+		{
+			name:    "login page",
+			body:    []byte(`<!DOCTYPE html><html><body><form action="/jsxsd/xk/LoginToXk">用户登录</form></body></html>`),
+			wantErr: ErrSessionExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := parseRoundIndex("R001", tt.body)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("parseRoundIndex() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRoundIndex() error = %v", err)
+			}
+			if entry.RoundID != "R001" || !slices.Equal(entry.Modules, tt.wantModules) {
+				t.Errorf("parseRoundIndex() = %+v, want modules %v", entry, tt.wantModules)
+			}
+		})
+	}
+}
@@ -29,8 +29,25 @@ var (
 		"xsxkFawxk",
 		"xsxkGgxxkxk",
 	}
+
+	// moduleNames 选课模块的中文名称。
+	moduleNames = map[string]string{
+		"xsxkKnjxk":   "专业内跨年级选课",
+		"xsxkBxqjhxk": "本学期计划选课",
+		"xsxkXxxk":    "选修选课",
+		"xsxkFawxk":   "计划外选课",
+		"xsxkGgxxkxk": "公选课选课",
+	}
 )
 
+// ModuleName 返回选课模块的中文名称，未知模块返回标识本身。
+func ModuleName(moduleType string) string {
+	if name, ok := moduleNames[moduleType]; ok {
+		return name
+	}
+	return moduleType
+}
+
 // CourseInfo 对应选课搜索接口返回的单条课程数据。
 type CourseInfo struct {
 	Kch      string `json:"kch"`
@@ -45,6 +62,10 @@ type CourseInfo struct {
 	Dwmc     string `json:"dwmc"`
 	Ktmc     string `json:"ktmc"`
 	Skdd     string `json:"skdd"`
+	Ctsm     string `json:"ctsm"` // 与已选课程的冲突说明
+
+	// Module 搜索到该课程的选课模块标识，由 SearchModules 填充
+	Module string `json:"module,omitempty"`
 }
 
 // UniqueKey 课程唯一标识: {jx02id}_{jx0404id}。
@@ -123,16 +144,106 @@ func SearchModule(ctx context.Context, client *http.Client, moduleType string, c
 	return result.AaData, nil
 }
 
+// ParseModules 将模块标识或中文名称解析为模块标识，大小写不敏感。
+func ParseModules(items []string) ([]string, error) {
+	modules := make([]string, 0, len(items))
+	seen := make(map[string]struct{})
+	for _, item := range items {
+		module := ""
+		for _, candidate := range ModuleTypes {
+			if strings.EqualFold(item, candidate) || item == moduleNames[candidate] {
+				module = candidate
+				break
+			}
+		}
+		if module == "" {
+			return nil, fmt.Errorf("未知的选课模块: %s（可选: %s）", item, strings.Join(ModuleTypes, ", "))
+		}
+		if _, ok := seen[module]; ok {
+			continue
+		}
+		seen[module] = struct{}{}
+		modules = append(modules, module)
+	}
+	return modules, nil
+}
+
 // SearchAllModules 搜索全部五个模块并按唯一键去重。
 func SearchAllModules(ctx context.Context, client *http.Client, courseKeyword string) ([]CourseInfo, error) {
+	return SearchModules(ctx, client, ModuleTypes, courseKeyword)
+}
+
+// ModuleError 单个选课模块的搜索错误
+type ModuleError struct {
+	Module string
+	Err    error
+}
+
+func (e *ModuleError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ModuleError) Unwrap() error {
+	return e.Err
+}
+
+// SearchError 部分或全部模块搜索失败
+type SearchError struct {
+	Errors []*ModuleError
+}
+
+func (e *SearchError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("%d 个模块搜索失败: %s", len(e.Errors), strings.Join(messages, "; "))
+}
+
+func (e *SearchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Modules 返回搜索失败的模块标识
+func (e *SearchError) Modules() []string {
+	modules := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		modules = append(modules, err.Module)
+	}
+	return modules
+}
+
+// IsPartialSearch 判断 SearchModules 是否只有部分模块失败，此时返回的课程仍可使用。
+func IsPartialSearch(courses []CourseInfo, err error) bool {
+	var searchErr *SearchError
+	return courses != nil && errors.As(err, &searchErr)
+}
+
+// SearchModules 依次搜索指定模块并按唯一键去重。
+// 单个模块失败时记录错误并继续搜索其余模块，返回已搜到的课程与 *SearchError；
+// 全部模块失败时返回 nil 与 *SearchError。会话失效时立即返回，其余模块同样无法访问。
+func SearchModules(ctx context.Context, client *http.Client, modules []string, courseKeyword string) ([]CourseInfo, error) {
 	uniq := make(map[string]CourseInfo)
-	for _, moduleType := range ModuleTypes {
+	var failed []*ModuleError
+	for _, moduleType := range modules {
 		courses, err := SearchModule(ctx, client, moduleType, courseKeyword)
 		if err != nil {
-			return nil, err
+			if IsSessionExpired(err) || ctx.Err() != nil {
+				return nil, err
+			}
+			failed = append(failed, &ModuleError{Module: moduleType, Err: err})
 		}
 		for _, course := range courses {
-			uniq[course.UniqueKey()] = course
+			key := course.UniqueKey()
+			if _, exists := uniq[key]; exists {
+				continue
+			}
+			course.Module = moduleType
+			uniq[key] = course
 		}
 
 		// 降低接口压力，避免单轮请求过于密集。
@@ -143,10 +254,17 @@ func SearchAllModules(ctx context.Context, client *http.Client, courseKeyword st
 		}
 	}
 
+	if len(failed) > 0 && len(failed) == len(modules) {
+		return nil, &SearchError{Errors: failed}
+	}
+
 	result := make([]CourseInfo, 0, len(uniq))
 	for _, course := range uniq {
 		result = append(result, course)
 	}
+	if len(failed) > 0 {
+		return result, &SearchError{Errors: failed}
+	}
 	return result, nil
 }
 
@@ -0,0 +1,133 @@
+package jwxt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// rewriteTransport 将发往教务系统的请求转发到测试服务器
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// stubClient 创建访问 handler 的 HTTP 客户端，不跟随重定向
+func stubClient(t *testing.T, handler http.HandlerFunc) *http.Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	target, _ := url.Parse(server.URL)
+	return &http.Client{
+		Transport: rewriteTransport{target: target},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func TestSearchModules(t *testing.T) {
+	// 各模块的响应: 课程 JSON、HTTP 状态码或重定向
+	responses := map[string]string{
+		"xsxkKnjxk":   `{"aaData": [{"kch": "A001", "jx02id": "J1", "jx0404id": "1"}]}`,
+		"xsxkBxqjhxk": `{"aaData": [{"kch": "A001", "jx02id": "J1", "jx0404id": "1"}, {"kch": "B002", "jx02id": "J2", "jx0404id": "2"}]}`,
+		"xsxkXxxk":    "500",
+		"xsxkFawxk":   "{broken",
+		"xsxkGgxxkxk": "302",
+	}
+	client := stubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		module := strings.TrimPrefix(r.URL.Path, searchPathPrefix)
+		switch response := responses[module]; response {
+		case "500":
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		case "302":
+			http.Redirect(w, r, "/jsxsd/", http.StatusFound)
+		default:
+			io.WriteString(w, response)
+		}
+	})
+
+	tests := []struct {
+		name        string
+		modules     []string
+		wantKeys    []string // nil 表示不返回结果
+		wantFailed  []string
+		wantSession bool
+	}{
+		{
+			name:     "all succeed",
+			modules:  []string{"xsxkKnjxk", "xsxkBxqjhxk"},
+			wantKeys: []string{"J1_1", "J2_2"},
+		},
+		{
+			name:       "partial failure keeps other modules",
+			modules:    []string{"xsxkXxxk", "xsxkKnjxk", "xsxkFawxk"},
+			wantKeys:   []string{"J1_1"},
+			wantFailed: []string{"xsxkXxxk", "xsxkFawxk"},
+		},
+		{
+			name:       "all modules fail",
+			modules:    []string{"xsxkXxxk", "xsxkFawxk"},
+			wantFailed: []string{"xsxkXxxk", "xsxkFawxk"},
+		},
+		{
+			name:        "session expired aborts",
+			modules:     []string{"xsxkKnjxk", "xsxkGgxxkxk", "xsxkBxqjhxk"},
+			wantSession: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			courses, err := SearchModules(context.Background(), client, tt.modules, "A001")
+
+			if tt.wantSession {
+				if !IsSessionExpired(err) || courses != nil {
+					t.Fatalf("SearchModules() = %v, %v, want ErrSessionExpired", courses, err)
+				}
+				return
+			}
+
+			var searchErr *SearchError
+			if len(tt.wantFailed) == 0 {
+				if err != nil {
+					t.Fatalf("SearchModules() error = %v", err)
+				}
+			} else if !errors.As(err, &searchErr) || !slices.Equal(searchErr.Modules(), tt.wantFailed) {
+				t.Fatalf("SearchModules() error = %v, want failed modules %v", err, tt.wantFailed)
+			}
+			if partial := IsPartialSearch(courses, err); partial != (tt.wantKeys != nil && err != nil) {
+				t.Errorf("IsPartialSearch() = %v", partial)
+			}
+
+			if tt.wantKeys == nil {
+				if courses != nil {
+					t.Errorf("courses = %v, want nil", courses)
+				}
+				return
+			}
+			var keys []string
+			for _, course := range courses {
+				keys = append(keys, course.UniqueKey())
+				if course.Module == "" {
+					t.Errorf("course %s 未记录模块", course.UniqueKey())
+				}
+			}
+			slices.Sort(keys)
+			if !slices.Equal(keys, tt.wantKeys) {
+				t.Errorf("courses = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
@@ -65,3 +65,11 @@ func Init() (func(), error) {
 	}
 	return cleanup, nil
 }
+
+// Redact 隐藏敏感值，仅保留长度信息，用于在日志中输出 Cookie、Token 等。
+func Redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "***(" + strconv.Itoa(len(value)) + ")"
+}
@@ -0,0 +1,160 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/config"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/jwxt"
+)
+
+// RoundResult 一轮查询的结果
+type RoundResult struct {
+	At        time.Time
+	Duration  time.Duration
+	Courses   int   // 查询到的课程数
+	Increased int   // 余量增加的课程数
+	Baseline  bool  // 本轮用于建立首轮基线
+	Err       error // 跳过本轮的原因，如轮次暂不可用、查询失败
+	NotifyErr error // 推送失败的原因
+}
+
+// Status 监控运行状态，供远程查看
+type Status struct {
+	Paused       bool
+	Round        string // 当前轮次 ID，未进入时为空
+	RoundErr     error  // 轮次暂不可用的原因
+	Keywords     int    // 监控的关键词数
+	PollInterval time.Duration
+	LastRound    RoundResult   // 最近一轮的结果，At 为零值表示尚未执行
+	LastError    error         // 最近一次查询或推送错误
+	LastErrorAt  time.Time     // LastError 发生的时间
+	SessionAge   time.Duration // 当前会话已存活的时长，未知时为 0
+	Outbox       int           // 尚未送达的推送批次数
+}
+
+// Status 返回当前运行状态。
+func (m *Monitor) Status() Status {
+	status := Status{
+		Paused:       m.paused.Load(),
+		Keywords:     len(m.keywords()),
+		PollInterval: m.pollInterval(),
+		SessionAge:   m.casClient.SessionAge(),
+		Outbox:       m.outbox.Len(),
+	}
+
+	m.roundMu.Lock()
+	if m.round != nil {
+		status.Round = m.round.RoundID
+	}
+	status.RoundErr = m.roundErr
+	m.roundMu.Unlock()
+
+	m.statusMu.Lock()
+	status.LastRound = m.lastRound
+	status.LastError = m.lastError
+	status.LastErrorAt = m.errorAt
+	m.statusMu.Unlock()
+	return status
+}
+
+func (m *Monitor) recordRound(result RoundResult) {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+
+	m.lastRound = result
+	if err := result.Err; err != nil {
+		m.lastError, m.errorAt = err, result.At
+	} else if err := result.NotifyErr; err != nil {
+		m.lastError, m.errorAt = fmt.Errorf("推送失败: %w", err), result.At
+	}
+}
+
+// Pause 暂停持续轮询，会话保活不受影响。返回 false 表示此前已暂停。
+func (m *Monitor) Pause() bool {
+	if m.paused.Swap(true) {
+		return false
+	}
+	log.Printf("[INFO] 监控已暂停")
+	return true
+}
+
+// Resume 恢复持续轮询。返回 false 表示此前未暂停。
+func (m *Monitor) Resume() bool {
+	if !m.paused.Swap(false) {
+		return false
+	}
+	log.Printf("[INFO] 监控已恢复")
+	return true
+}
+
+// CheckNow 立即执行一轮查询与推送，等待正在进行的一轮结束后开始，暂停时同样执行。
+func (m *Monitor) CheckNow(ctx context.Context) (RoundResult, error) {
+	log.Printf("[INFO] 收到立即检查请求")
+	return m.runRound(ctx)
+}
+
+// ForceRelogin 立即重新登录一次并重新进入选课轮次，不做失败重试。
+// 登录仍受失败台账限制，避免远程操作导致账号被锁定。
+func (m *Monitor) ForceRelogin(ctx context.Context) error {
+	m.reloginMu.Lock()
+	defer m.reloginMu.Unlock()
+
+	log.Printf("[INFO] 收到强制重登请求")
+	cfg := m.currentConfig()
+	if err := m.casClient.Login(ctx, cfg.Username, cfg.Password, m.ocrClient); err != nil {
+		return err
+	}
+	m.lastRelogin = time.Now()
+	if err := m.casClient.SaveSession(); err != nil {
+		log.Printf("[WARN] 保存 session 失败: %v", err)
+	}
+	if err := m.EnterRound(ctx); err != nil && !jwxt.IsRoundUnavailable(err) {
+		return fmt.Errorf("登录成功，但进入选课轮次失败: %w", err)
+	}
+	return nil
+}
+
+// ReloadConfig 重新读取 .env，更新监控课程、搜索模块与轮询间隔。
+// 账号不同时拒绝重载；推送渠道等其余配置需重启后生效。
+func (m *Monitor) ReloadConfig() (*config.Config, error) {
+	cfg, err := config.Reload()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Username != m.currentConfig().Username {
+		return nil, fmt.Errorf("不支持在运行中切换账号，请重启程序")
+	}
+
+	var modules []string
+	if len(cfg.ModuleList) > 0 {
+		if modules, err = jwxt.ParseModules(cfg.ModuleList); err != nil {
+			return nil, fmt.Errorf("MODULE_LIST 配置错误: %w", err)
+		}
+	}
+
+	m.configMu.Lock()
+	m.config = cfg
+	m.modules = modules
+	m.configMu.Unlock()
+	log.Printf("[INFO] 配置已重载: 课程关键词=%d, 轮询间隔=%ds", len(cfg.CourseList), cfg.PollInterval)
+	return cfg, nil
+}
+
+func (m *Monitor) currentConfig() *config.Config {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+	return m.config
+}
+
+func (m *Monitor) configuredModules() []string {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+	return m.modules
+}
+
+func (m *Monitor) pollInterval() time.Duration {
+	return time.Duration(m.currentConfig().PollInterval) * time.Second
+}
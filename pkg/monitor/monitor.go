@@ -14,6 +14,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/cas"
@@ -23,7 +24,7 @@ import (
 )
 
 const (
-	defaultSnapshotPath = "data/last_result.json"
+	snapshotFileName = "last_result.json"
 )
 
 var remainingSeatNumberPattern = regexp.MustCompile(`-?\d+`)
@@ -33,15 +34,75 @@ type Monitor struct {
 	casClient    *cas.Client
 	client       *http.Client
 	config       *config.Config
-	notifier     *notify.Notifier
+	notifier     notify.Notifier
 	ocrClient    cas.OCRClient
 	lastResult   map[string]jwxt.CourseInfo
 	hasBaseline  bool
+	dataDir      string
 	snapshotPath string
+	// outbox 保存尚未送达的推送，推送失败时在之后的轮次重试
+	outbox *notify.Outbox
+
+	// reloginMu 串行化会话恢复，lastRelogin 用于跳过等待期间已被他人完成的重登
+	reloginMu   sync.Mutex
+	lastRelogin time.Time
+
+	// courses 提供 COURSE_LIST 之外需要监控的关键词，如用户订阅
+	courses CourseSource
+
+	// configMu 保护 config 与 modules，重载配置时整体替换
+	configMu sync.RWMutex
+	// modules 为 MODULE_LIST 指定的搜索模块，为空时按轮次开放的模块搜索
+	modules []string
+
+	// runSem 串行化轮询与远程触发的立即检查
+	runSem chan struct{}
+	paused atomic.Bool
+
+	statusMu  sync.Mutex
+	lastRound RoundResult
+	lastError error
+	errorAt   time.Time
+
+	// roundMu 保护当前轮次状态；roundErr 记录轮次暂不可选的原因，下一轮查询前会重新尝试进入
+	roundMu  sync.Mutex
+	round    *jwxt.RoundEntry
+	roundErr error
+	// roundModules 按轮次缓存页面中解析到的开放模块，再次进入时页面解析失败可沿用
+	roundModules map[string][]string
+}
+
+// CourseSource 提供额外的监控关键词，每轮查询前读取，需支持并发调用。
+type CourseSource interface {
+	Courses() []string
+}
+
+// Option 定义监控器的可选配置。
+type Option func(*Monitor)
+
+// WithOCRClient 指定会话恢复时使用的验证码识别客户端，默认按 OCR_API_URL 创建 ddddocr 客户端。
+func WithOCRClient(ocrClient cas.OCRClient) Option {
+	return func(m *Monitor) {
+		m.ocrClient = ocrClient
+	}
+}
+
+// WithDataDir 设置运行时数据目录，快照按账号存放于 <数据目录>/<账号>/last_result.json。
+func WithDataDir(dir string) Option {
+	return func(m *Monitor) {
+		m.dataDir = dir
+	}
+}
+
+// WithCourseSource 设置额外的监控关键词来源，与 COURSE_LIST 合并后一起监控。
+func WithCourseSource(src CourseSource) Option {
+	return func(m *Monitor) {
+		m.courses = src
+	}
 }
 
 // New 创建监控器，并尝试加载历史快照。
-func New(casClient *cas.Client, cfg *config.Config, notifier *notify.Notifier) (*Monitor, error) {
+func New(casClient *cas.Client, cfg *config.Config, notifier notify.Notifier, opts ...Option) (*Monitor, error) {
 	if casClient == nil {
 		return nil, fmt.Errorf("casClient 不能为空")
 	}
@@ -52,19 +113,48 @@ func New(casClient *cas.Client, cfg *config.Config, notifier *notify.Notifier) (
 		return nil, fmt.Errorf("notifier 不能为空")
 	}
 
-	// 创建 OCR 客户端
-	ocrClient := cas.NewDefaultOCRClient(cfg.OCRApiURL)
-
 	m := &Monitor{
 		casClient:    casClient,
 		client:       casClient.GetClient(),
 		config:       cfg,
 		notifier:     notifier,
-		ocrClient:    ocrClient,
 		lastResult:   make(map[string]jwxt.CourseInfo),
-		snapshotPath: defaultSnapshotPath,
+		dataDir:      cas.DefaultDataDir,
+		roundModules: make(map[string][]string),
+		runSem:       make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if len(cfg.ModuleList) > 0 {
+		modules, err := jwxt.ParseModules(cfg.ModuleList)
+		if err != nil {
+			return nil, fmt.Errorf("MODULE_LIST 配置错误: %w", err)
+		}
+		m.modules = modules
+	}
+
+	m.snapshotPath = filepath.Join(cas.AccountDir(m.dataDir, cfg.Username), snapshotFileName)
+	legacyPath := filepath.Join(m.dataDir, snapshotFileName)
+	if migrated, err := cas.MigrateLegacyFile(legacyPath, m.snapshotPath); err != nil {
+		log.Printf("[WARN] 迁移旧版快照失败: %v", err)
+	} else if migrated {
+		log.Printf("[INFO] 已将旧版快照迁移到: %s", m.snapshotPath)
+	}
+	if m.ocrClient == nil {
+		m.ocrClient = cas.NewDefaultOCRClient(cfg.OCRApiURL)
 	}
 
+	outbox, err := notify.OpenOutbox(filepath.Join(cas.AccountDir(m.dataDir, cfg.Username), notify.OutboxFileName))
+	if err != nil {
+		return nil, err
+	}
+	if pending := outbox.Len(); pending > 0 {
+		log.Printf("[INFO] 存在 %d 批未送达的推送，将在下一轮重试", pending)
+	}
+	m.outbox = outbox
+
 	snapshot, err := m.loadSnapshot()
 	if err == nil {
 		m.lastResult = snapshot
@@ -86,45 +176,199 @@ func (m *Monitor) Run(ctx context.Context) error {
 	default:
 	}
 
-	log.Printf("[INFO] 监控启动: 单次执行模式, 课程关键词=%d", len(m.config.CourseList))
-	m.runRound(ctx)
+	log.Printf("[INFO] 监控启动: 单次执行模式, 课程关键词=%d", len(m.keywords()))
+	if _, err := m.runRound(ctx); err != nil {
+		return err
+	}
 	log.Printf("[INFO] 监控结束: 单次执行完成")
 	return nil
 }
 
-func (m *Monitor) runRound(ctx context.Context) {
-	startedAt := time.Now()
+// RunLoop 按 PollInterval 间隔持续执行监控，直到 ctx 取消。
+func (m *Monitor) RunLoop(ctx context.Context) error {
+	log.Printf("[INFO] 监控启动: 持续轮询模式, 间隔=%s, 课程关键词=%d", m.pollInterval(), len(m.keywords()))
+
+	for {
+		if m.paused.Load() {
+			log.Printf("[DEBUG] 监控已暂停，跳过本轮")
+		} else if _, err := m.runRound(ctx); err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(m.pollInterval())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Printf("[INFO] 监控结束: %v", ctx.Err())
+			return nil
+		case <-timer.C:
+		}
+	}
+}
 
-	current, err := m.queryCurrentCourses(ctx)
+// EnterRound 获取并进入当前选课轮次，记录本轮开放的选课模块。
+// 未找到轮次或无法确认已进入时记录警告并继续直接搜索；
+// 轮次未开始、已结束或不在名单中时返回错误，之后每轮查询前都会重新尝试进入。
+func (m *Monitor) EnterRound(ctx context.Context) error {
+	client := m.casClient.GetClient()
+	roundID, err := jwxt.GetSelectionRoundID(ctx, client)
 	if err != nil {
-		if jwxt.IsSessionExpired(err) {
-			log.Printf("[WARN] 检测到会话失效，准备重登: %v", err)
-			if reloginErr := m.reloginWithRetry(ctx); reloginErr != nil {
-				log.Printf("[ERROR] 会话恢复失败: %v", reloginErr)
+		if jwxt.IsSelectionRoundNotFound(err) {
+			log.Printf("[WARN] 未找到可用选课轮次，跳过轮次进入并直接执行搜索: %v", err)
+			m.setRound(nil, nil)
+			return nil
+		}
+		return fmt.Errorf("获取选课轮次失败: %w", err)
+	}
+
+	entry, err := jwxt.EnterSelectionRound(ctx, client, roundID)
+	if err != nil {
+		switch {
+		case jwxt.IsRoundUnavailable(err):
+			m.setRound(nil, err)
+			return err
+		case errors.Is(err, jwxt.ErrRoundNotEntered):
+			log.Printf("[WARN] 轮次 %s: %v，继续直接执行搜索", roundID, err)
+			m.setRound(&jwxt.RoundEntry{RoundID: roundID}, nil)
+			return nil
+		default:
+			return fmt.Errorf("进入选课轮次失败: %w", err)
+		}
+	}
+
+	m.setRound(entry, nil)
+	log.Printf("[INFO] 已进入选课轮次: %s, 开放模块: %s", roundID, moduleNames(entry.Modules))
+	if modules := m.configuredModules(); len(modules) > 0 {
+		log.Printf("[INFO] 按 MODULE_LIST 搜索模块: %s", moduleNames(modules))
+	}
+	return nil
+}
+
+// searchModules 返回本轮需要搜索的模块: MODULE_LIST > 轮次开放的模块 > 全部模块
+func (m *Monitor) searchModules() []string {
+	if modules := m.configuredModules(); len(modules) > 0 {
+		return modules
+	}
+	m.roundMu.Lock()
+	defer m.roundMu.Unlock()
+	if m.round != nil && len(m.round.Modules) > 0 {
+		return m.round.Modules
+	}
+	return jwxt.ModuleTypes
+}
+
+func moduleNames(modules []string) string {
+	names := make([]string, 0, len(modules))
+	for _, module := range modules {
+		names = append(names, jwxt.ModuleName(module))
+	}
+	return strings.Join(names, "、")
+}
+
+// Round 返回当前所在的选课轮次，未进入任何轮次时返回 nil。
+func (m *Monitor) Round() *jwxt.RoundEntry {
+	m.roundMu.Lock()
+	defer m.roundMu.Unlock()
+	return m.round
+}
+
+func (m *Monitor) setRound(entry *jwxt.RoundEntry, err error) {
+	m.roundMu.Lock()
+	defer m.roundMu.Unlock()
+
+	if entry != nil {
+		if len(entry.Modules) > 0 {
+			m.roundModules[entry.RoundID] = entry.Modules
+		} else if cached, ok := m.roundModules[entry.RoundID]; ok {
+			entry.Modules = cached
+			log.Printf("[INFO] 轮次 %s 沿用此前解析到的开放模块: %s", entry.RoundID, moduleNames(cached))
+		}
+	}
+	m.round = entry
+	m.roundErr = err
+}
+
+func (m *Monitor) roundUnavailable() bool {
+	m.roundMu.Lock()
+	defer m.roundMu.Unlock()
+	return m.roundErr != nil
+}
+
+// Relogin 重新登录并重新进入选课轮次，供会话保活在后台调用。
+func (m *Monitor) Relogin(ctx context.Context) error {
+	return m.reloginWithRetry(ctx)
+}
+
+// runRound 执行一轮查询与推送，仅在必须停止监控时（如账号密码错误、账号被锁定）返回错误
+func (m *Monitor) runRound(ctx context.Context) (RoundResult, error) {
+	select {
+	case m.runSem <- struct{}{}:
+		defer func() { <-m.runSem }()
+	case <-ctx.Done():
+		return RoundResult{}, ctx.Err()
+	}
+
+	result := RoundResult{At: time.Now()}
+	err := m.doRound(ctx, &result)
+	if err == nil {
+		m.deliverOutbox(ctx, &result)
+	}
+	result.Duration = time.Since(result.At)
+	m.recordRound(result)
+	return result, err
+}
+
+// doRound 执行一轮查询，余量增加的课程写入 outbox 后再推进快照，跳过本轮的原因记录在 result.Err 中
+func (m *Monitor) doRound(ctx context.Context, result *RoundResult) error {
+	startedAt := result.At
+
+	// 轮次此前暂不可选（如未到选课时间），先重新尝试进入
+	if m.roundUnavailable() {
+		if err := m.EnterRound(ctx); err != nil {
+			result.Err = err
+			if jwxt.IsSessionExpired(err) {
+				return m.recoverSession(ctx, err)
 			}
-			return
+			log.Printf("[WARN] 选课轮次暂不可用，跳过本轮: %v", err)
+			return nil
+		}
+	}
+
+	current, keywords, err := m.queryCurrentCourses(ctx)
+	if err != nil {
+		result.Err = err
+		if jwxt.IsSessionExpired(err) {
+			return m.recoverSession(ctx, err)
 		}
 		log.Printf("[ERROR] 本轮查询失败，已跳过: %v", err)
-		return
+		return nil
 	}
+	result.Courses = len(current)
 
-	increased := m.diffRemainingIncreased(current)
+	increased := m.diffRemainingIncreased(current, keywords)
 	if !m.hasBaseline {
 		m.lastResult = current
 		m.hasBaseline = true
+		result.Baseline = true
 		if err := m.saveSnapshot(current); err != nil {
 			log.Printf("[WARN] 保存首轮快照失败: %v", err)
 		}
 		log.Printf("[INFO] 首轮基线已建立: 当前课程=%d, 耗时=%s", len(current), time.Since(startedAt))
-		return
+		return nil
 	}
+	result.Increased = len(increased)
 
 	if len(increased) > 0 {
-		message := notify.FormatCoursesMessage(increased)
-		if err := m.notifier.BroadcastMessage(message); err != nil {
-			log.Printf("[ERROR] 余量增加课程推送失败: %v", err)
-		} else {
-			log.Printf("[INFO] 已推送余量增加课程: %d 条", len(increased))
+		batch := notify.Batch{Time: time.Now(), Events: increased}
+		if round := m.Round(); round != nil {
+			batch.Round = round.RoundID
+			for i := range batch.Events {
+				batch.Events[i].Round = round.RoundID
+			}
+		}
+		// 先持久化事件再推进快照，推送失败或进程退出时事件不会丢失
+		if err := m.outbox.Add(batch, notify.Channels(m.notifier)); err != nil {
+			log.Printf("[WARN] 保存待推送事件失败，进程退出前仍会尝试推送: %v", err)
 		}
 	}
 
@@ -133,31 +377,97 @@ func (m *Monitor) runRound(ctx context.Context) {
 		log.Printf("[WARN] 保存快照失败: %v", err)
 	}
 	log.Printf("[INFO] 本轮完成: 总课程=%d, 余量增加=%d, 耗时=%s", len(current), len(increased), time.Since(startedAt))
+	return nil
 }
 
-func (m *Monitor) queryCurrentCourses(ctx context.Context) (map[string]jwxt.CourseInfo, error) {
+// deliverOutbox 推送 outbox 中的全部批次，失败的渠道留待下一轮重试
+func (m *Monitor) deliverOutbox(ctx context.Context, result *RoundResult) {
+	pending := m.outbox.Len()
+	if pending == 0 {
+		return
+	}
+	maxAge := time.Duration(m.currentConfig().OutboxMaxAge) * time.Minute
+	if err := m.outbox.Deliver(ctx, m.notifier, maxAge); err != nil {
+		result.NotifyErr = err
+		log.Printf("[ERROR] 余量增加课程推送失败，剩余 %d 批将在下一轮重试: %v", m.outbox.Len(), err)
+		return
+	}
+	log.Printf("[INFO] 已推送余量增加课程: %d 批", pending)
+}
+
+// recoverSession 在检测到会话失效后重登，仅在需要停止监控时返回错误
+func (m *Monitor) recoverSession(ctx context.Context, cause error) error {
+	log.Printf("[WARN] 检测到会话失效，准备重登: %v", cause)
+	m.casClient.MarkSessionExpired()
+	if err := m.reloginWithRetry(ctx); err != nil {
+		if loginFailureAction(err) == reloginStop {
+			return fmt.Errorf("会话恢复失败: %w", err)
+		}
+		log.Printf("[ERROR] 会话恢复失败: %v", err)
+	}
+	return nil
+}
+
+// keywords 返回本轮监控的关键词: COURSE_LIST 在前，其后为 CourseSource 中的新增关键词
+func (m *Monitor) keywords() []string {
+	keywords := append([]string(nil), m.currentConfig().CourseList...)
+	if m.courses == nil {
+		return keywords
+	}
+	seen := make(map[string]bool, len(keywords))
+	for _, kw := range keywords {
+		seen[kw] = true
+	}
+	for _, kw := range m.courses.Courses() {
+		if !seen[kw] {
+			seen[kw] = true
+			keywords = append(keywords, kw)
+		}
+	}
+	return keywords
+}
+
+// SearchCourses 按关键词搜索当前轮次可选的课程，供机器人指令查询。
+// 部分模块搜索失败时返回其余模块的结果。
+func (m *Monitor) SearchCourses(ctx context.Context, keyword string) ([]jwxt.CourseInfo, error) {
+	courses, err := jwxt.SearchModules(ctx, m.client, m.searchModules(), keyword)
+	if jwxt.IsPartialSearch(courses, err) {
+		log.Printf("[WARN] 课程[%s]部分模块搜索失败，仅返回其余模块的结果: %v", keyword, err)
+		return courses, nil
+	}
+	return courses, err
+}
+
+// queryCurrentCourses 并发搜索全部关键词，返回按唯一键去重的课程，
+// 以及每门课程命中的关键词（按 keywords() 中的顺序排列）。
+// 部分模块搜索失败时，这些模块沿用上一轮快照中的课程，避免其基线丢失。
+func (m *Monitor) queryCurrentCourses(ctx context.Context) (map[string]jwxt.CourseInfo, map[string][]string, error) {
 	type result struct {
 		courses []jwxt.CourseInfo
 		err     error
 		keyword string
+		index   int
 	}
 
-	resultCh := make(chan result, len(m.config.CourseList))
+	watched := m.keywords()
+	resultCh := make(chan result, len(watched))
 	var wg sync.WaitGroup
+	modules := m.searchModules()
 
 	// 启动并发搜索
-	for _, keyword := range m.config.CourseList {
+	for i, keyword := range watched {
 		wg.Add(1)
-		go func(kw string) {
+		go func(i int, kw string) {
 			defer wg.Done()
 
-			courses, err := jwxt.SearchAllModules(ctx, m.client, kw)
+			courses, err := jwxt.SearchModules(ctx, m.client, modules, kw)
 			resultCh <- result{
 				courses: courses,
 				err:     err,
 				keyword: kw,
+				index:   i,
 			}
-		}(keyword)
+		}(i, keyword)
 	}
 
 	// 等待所有搜索完成后关闭 channel
@@ -168,9 +478,18 @@ func (m *Monitor) queryCurrentCourses(ctx context.Context) (map[string]jwxt.Cour
 
 	// 收集结果
 	current := make(map[string]jwxt.CourseInfo)
+	matched := make(map[string][]int)
+	failedModules := make(map[string]bool)
 	for res := range resultCh {
-		if res.err != nil {
-			return nil, fmt.Errorf("课程[%s]搜索失败: %w", res.keyword, res.err)
+		if jwxt.IsPartialSearch(res.courses, res.err) {
+			var searchErr *jwxt.SearchError
+			errors.As(res.err, &searchErr)
+			for _, module := range searchErr.Modules() {
+				failedModules[module] = true
+			}
+			log.Printf("[WARN] 课程[%s]部分模块搜索失败: %v", res.keyword, res.err)
+		} else if res.err != nil {
+			return nil, nil, fmt.Errorf("课程[%s]搜索失败: %w", res.keyword, res.err)
 		}
 		for _, course := range res.courses {
 			key := course.UniqueKey()
@@ -178,14 +497,28 @@ func (m *Monitor) queryCurrentCourses(ctx context.Context) (map[string]jwxt.Cour
 				continue
 			}
 			current[key] = course
+			matched[key] = append(matched[key], res.index)
+		}
+	}
+
+	for key, course := range m.lastResult {
+		if _, ok := current[key]; !ok && failedModules[course.Module] {
+			current[key] = course
 		}
 	}
 
-	return current, nil
+	keywords := make(map[string][]string, len(matched))
+	for key, indexes := range matched {
+		sort.Ints(indexes)
+		for _, idx := range indexes {
+			keywords[key] = append(keywords[key], watched[idx])
+		}
+	}
+	return current, keywords, nil
 }
 
-func (m *Monitor) diffRemainingIncreased(current map[string]jwxt.CourseInfo) []jwxt.CourseInfo {
-	increased := make([]jwxt.CourseInfo, 0)
+func (m *Monitor) diffRemainingIncreased(current map[string]jwxt.CourseInfo, keywords map[string][]string) []notify.Event {
+	increased := make([]notify.Event, 0)
 	for key, course := range current {
 		lastCourse, exists := m.lastResult[key]
 		if !exists {
@@ -199,16 +532,31 @@ func (m *Monitor) diffRemainingIncreased(current map[string]jwxt.CourseInfo) []j
 			continue
 		}
 		if currentRemaining > lastRemaining {
-			increased = append(increased, course)
+			increased = append(increased, notify.Event{
+				Type:     notify.EventSeatsIncreased,
+				Course:   course,
+				Keyword:  firstKeyword(keywords[key]),
+				Keywords: keywords[key],
+				Module:   course.Module,
+				OldSeats: lastRemaining,
+				NewSeats: currentRemaining,
+			})
 		}
 	}
 
 	sort.Slice(increased, func(i, j int) bool {
-		return increased[i].UniqueKey() < increased[j].UniqueKey()
+		return increased[i].Course.UniqueKey() < increased[j].Course.UniqueKey()
 	})
 	return increased
 }
 
+func firstKeyword(keywords []string) string {
+	if len(keywords) == 0 {
+		return ""
+	}
+	return keywords[0]
+}
+
 func parseRemainingSeats(value string) (int, bool) {
 	value = strings.TrimSpace(value)
 	if value == "" {
@@ -235,7 +583,51 @@ func parseRemainingSeats(value string) (int, bool) {
 	return n, true
 }
 
+// reloginAction 登录失败后的处理方式
+type reloginAction int
+
+const (
+	reloginRetry   reloginAction = iota // 按指数退避重试
+	reloginBackoff                      // 教务系统暂不可用，等待 serviceBackoff 后重试
+	reloginStop                         // 需要人工处理，停止重试
+)
+
+// serviceBackoff 教务系统维护或不在开放时间时的重试间隔
+const serviceBackoff = 10 * time.Minute
+
+// loginFailureAction 根据登录失败类型决定重试、退避还是停止
+func loginFailureAction(err error) reloginAction {
+	if errors.Is(err, cas.ErrCredentialsRejected) {
+		return reloginStop
+	}
+	switch cas.LoginErrorTypeOf(err) {
+	case cas.LoginErrorPassword, cas.LoginErrorPasswordExpired, cas.LoginErrorLocked:
+		return reloginStop
+	case cas.LoginErrorOutOfService, cas.LoginErrorMaintenance:
+		return reloginBackoff
+	default:
+		return reloginRetry
+	}
+}
+
 func (m *Monitor) reloginWithRetry(ctx context.Context) error {
+	requestedAt := time.Now()
+	m.reloginMu.Lock()
+	defer m.reloginMu.Unlock()
+
+	if m.lastRelogin.After(requestedAt) {
+		log.Printf("[INFO] 会话已在等待期间恢复，跳过本次重登")
+		return nil
+	}
+
+	if err := m.relogin(ctx); err != nil {
+		return err
+	}
+	m.lastRelogin = time.Now()
+	return nil
+}
+
+func (m *Monitor) relogin(ctx context.Context) error {
 	backoff := 2 * time.Second
 	for attempt := 1; ; attempt++ {
 		select {
@@ -245,36 +637,50 @@ func (m *Monitor) reloginWithRetry(ctx context.Context) error {
 		}
 
 		log.Printf("[INFO] 会话恢复第 %d 次尝试", attempt)
-		if err := m.casClient.Login(ctx, m.config.Username, m.config.Password, m.ocrClient); err != nil {
+		cfg := m.currentConfig()
+		if err := m.casClient.Login(ctx, cfg.Username, cfg.Password, m.ocrClient); err != nil {
+			var blocked *cas.LoginBlockedError
+			if errors.As(err, &blocked) && !blocked.Until.IsZero() {
+				wait := time.Until(blocked.Until)
+				log.Printf("[WARN] %v，等待 %s 后再尝试", err, wait.Round(time.Second))
+				if err := sleepContext(ctx, wait); err != nil {
+					return err
+				}
+				continue
+			}
+
+			switch loginFailureAction(err) {
+			case reloginStop:
+				// 账号密码错误、账号锁定等需要人工处理，继续重试只会加重锁定
+				return err
+			case reloginBackoff:
+				log.Printf("[WARN] 重新登录失败: %v，%s 后再尝试", err, serviceBackoff)
+				if err := sleepContext(ctx, serviceBackoff); err != nil {
+					return err
+				}
+				continue
+			}
 			log.Printf("[ERROR] 重新登录失败: %v", err)
 		} else {
 			// 重新登录成功后保存 session
 			if err := m.casClient.SaveSession(); err != nil {
 				log.Printf("[WARN] 保存 session 失败: %v", err)
 			}
-			roundID, err := jwxt.GetSelectionRoundID(ctx, m.casClient.GetClient())
-			if err != nil {
-				if jwxt.IsSelectionRoundNotFound(err) {
-					m.client = m.casClient.GetClient()
-					log.Printf("[WARN] 会话恢复成功，但未找到可用选课轮次，跳过轮次进入并继续搜索: %v", err)
+			if err := m.EnterRound(ctx); err != nil {
+				if jwxt.IsRoundUnavailable(err) {
+					// 会话已恢复，轮次状态会在之后每轮查询前重新检查
+					log.Printf("[WARN] 会话恢复成功，但选课轮次暂不可用: %v", err)
 					return nil
 				}
-				log.Printf("[ERROR] 重新获取轮次失败: %v", err)
-			} else if err := jwxt.EnterSelectionRound(ctx, m.casClient.GetClient(), roundID); err != nil {
 				log.Printf("[ERROR] 重新进入轮次失败: %v", err)
 			} else {
-				m.client = m.casClient.GetClient()
-				log.Printf("[INFO] 会话恢复成功，已进入轮次: %s", roundID)
+				log.Printf("[INFO] 会话恢复成功")
 				return nil
 			}
 		}
 
-		timer := time.NewTimer(backoff)
-		select {
-		case <-ctx.Done():
-			timer.Stop()
-			return ctx.Err()
-		case <-timer.C:
+		if err := sleepContext(ctx, backoff); err != nil {
+			return err
 		}
 
 		if backoff < time.Minute {
@@ -286,6 +692,21 @@ func (m *Monitor) reloginWithRetry(ctx context.Context) error {
 	}
 }
 
+// sleepContext 等待 d，ctx 取消时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 func (m *Monitor) saveSnapshot(data map[string]jwxt.CourseInfo) error {
 	dir := filepath.Dir(m.snapshotPath)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
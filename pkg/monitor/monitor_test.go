@@ -0,0 +1,185 @@
+package monitor
+
+import (
+	"context"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/cas"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/config"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/jwxt"
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/notify"
+)
+
+// stubNotifier 丢弃所有推送
+type stubNotifier struct{}
+
+func (stubNotifier) Name() string                                   { return "stub" }
+func (stubNotifier) Send(ctx context.Context, b notify.Batch) error { return nil }
+
+// newTestMonitor 在临时数据目录中创建监控器
+func newTestMonitor(t *testing.T, dataDir string, cfg *config.Config, opts ...cas.ClientOption) *Monitor {
+	t.Helper()
+	opts = append([]cas.ClientOption{cas.WithDataDir(dataDir), cas.WithAccount(cfg.Username)}, opts...)
+	casClient, err := cas.NewClient(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(casClient, cfg, stubNotifier{}, WithDataDir(dataDir))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return m
+}
+
+func TestNewMigratesLegacySnapshot(t *testing.T) {
+	dataDir := t.TempDir()
+	legacyPath := filepath.Join(dataDir, snapshotFileName)
+	snapshot := `{"A001-01": {"kch": "A001", "kcmc": "高等数学", "syrs": "3"}}`
+	if err := os.WriteFile(legacyPath, []byte(snapshot), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newTestMonitor(t, dataDir, &config.Config{Username: "2021001"})
+
+	if want := filepath.Join(dataDir, "2021001", snapshotFileName); m.snapshotPath != want {
+		t.Errorf("snapshotPath = %s, want %s", m.snapshotPath, want)
+	}
+	if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+		t.Errorf("旧版快照仍在数据目录根部: %v", err)
+	}
+	course, ok := m.lastResult["A001-01"]
+	if !ok || course.Kcmc != "高等数学" || course.Syrs != "3" {
+		t.Errorf("lastResult = %+v, want migrated snapshot", m.lastResult)
+	}
+}
+
+// withStubServer 将发往教务系统的请求转发到 handler
+func withStubServer(t *testing.T, handler http.HandlerFunc) cas.ClientOption {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	target, _ := url.Parse(server.URL)
+	return cas.WithTransportWrapper(func(base http.RoundTripper) http.RoundTripper {
+		return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+			return base.RoundTrip(req)
+		})
+	})
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+const roundListPage = `<html><body><table id="tbKxkc">
+<tr><th>轮次</th><th>操作</th></tr>
+<tr><td>2025-2026-1 第一轮</td><td><a href="/jsxsd/xsxk/xklc_view?jx0502zbid=R001">进入选课</a></td></tr>
+</table></body></html>`
+
+func TestSearchModulesSelection(t *testing.T) {
+	tests := []struct {
+		name       string
+		moduleList []string
+		roundList  string // 轮次列表页，空表示没有轮次
+		roundIndex string // 选课首页主体
+		want       []string
+	}{
+		{
+			name:       "MODULE_LIST wins",
+			moduleList: []string{"选修选课"},
+			roundList:  roundListPage,
+			roundIndex: `<li onclick="comeInKnjxk()">专业内跨年级选课</li>`,
+			want:       []string{"xsxkXxxk"},
+		},
+		{
+			name:       "modules offered by round",
+			roundList:  roundListPage,
+			roundIndex: `<li onclick="comeInXxxk()">选修选课</li><li onclick="comeInBxqjhxk()">本学期计划选课</li>`,
+			want:       []string{"xsxkBxqjhxk", "xsxkXxxk"},
+		},
+		{
+			name:       "unknown module is skipped",
+			roundList:  roundListPage,
+			roundIndex: `<li onclick="comeInTyxk()">体育选课</li><li onclick="comeInKnjxk()">专业内跨年级选课</li>`,
+			want:       []string{"xsxkKnjxk"},
+		},
+		{
+			name:       "only unknown modules falls back to all",
+			roundList:  roundListPage,
+			roundIndex: `<li onclick="comeInTyxk()">体育选课</li>`,
+			want:       jwxt.ModuleTypes,
+		},
+		{
+			name: "no round found",
+			want: jwxt.ModuleTypes,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := withStubServer(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case jwxt.RoundListPath:
+					io.WriteString(w, tt.roundList)
+				case jwxt.RoundIndexPath:
+					io.WriteString(w, "<html><body>"+tt.roundIndex+"</body></html>")
+				default:
+					io.WriteString(w, "<html></html>")
+				}
+			})
+			cfg := &config.Config{Username: "2021001", ModuleList: tt.moduleList}
+			m := newTestMonitor(t, t.TempDir(), cfg, stub)
+
+			if err := m.EnterRound(context.Background()); err != nil {
+				t.Fatalf("EnterRound() error = %v", err)
+			}
+			if got := m.searchModules(); !slices.Equal(got, tt.want) {
+				t.Errorf("searchModules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryCurrentCoursesPartialFailure(t *testing.T) {
+	stub := withStubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jsxsd/xsxkkc/xsxkKnjxk":
+			io.WriteString(w, `{"aaData": [{"kch": "A001", "jx02id": "J1", "jx0404id": "1", "syrs": "2"}]}`)
+		default:
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+	})
+	cfg := &config.Config{Username: "2021001", CourseList: []string{"A001"}, ModuleList: []string{"xsxkKnjxk", "xsxkXxxk"}}
+	m := newTestMonitor(t, t.TempDir(), cfg, stub)
+	m.lastResult = map[string]jwxt.CourseInfo{
+		"J1_1": {Kch: "A001", Jx02id: "J1", Jx0404id: "1", Syrs: "1", Module: "xsxkKnjxk"},
+		"J3_3": {Kch: "A001", Jx02id: "J3", Jx0404id: "3", Syrs: "0", Module: "xsxkXxxk"},
+		"J4_4": {Kch: "A001", Jx02id: "J4", Jx0404id: "4", Syrs: "0", Module: "xsxkKnjxk"},
+	}
+
+	current, _, err := m.queryCurrentCourses(context.Background())
+	if err != nil {
+		t.Fatalf("queryCurrentCourses() error = %v", err)
+	}
+	// 成功模块取最新结果，失败模块沿用上一轮快照，成功模块中消失的课程不再保留
+	if got := slices.Sorted(maps.Keys(current)); !slices.Equal(got, []string{"J1_1", "J3_3"}) {
+		t.Fatalf("current = %v, want [J1_1 J3_3]", got)
+	}
+	if current["J1_1"].Syrs != "2" {
+		t.Errorf("J1_1 余量 = %s, want 最新值 2", current["J1_1"].Syrs)
+	}
+
+	// 全部模块失败时本轮查询失败
+	m.modules = []string{"xsxkXxxk"}
+	if _, _, err := m.queryCurrentCourses(context.Background()); err == nil {
+		t.Error("queryCurrentCourses() error = nil, want error when all modules fail")
+	}
+}
@@ -0,0 +1,205 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/time/rate"
+)
+
+// OneBot 发送控制的默认值
+const (
+	// DefaultMaxMessageLength 单条文本消息的最大字符数，超出时按课程分段发送
+	DefaultMaxMessageLength = 1500
+	// DefaultRateLimit 全部群与私聊合计每分钟最多发送的消息数
+	DefaultRateLimit = 20
+	// DefaultTargetRateLimit 每个群或私聊每分钟最多发送的消息数
+	DefaultTargetRateLimit = 10
+	// DefaultMaxRetries 限流或临时失败时的最大重试次数
+	DefaultMaxRetries = 3
+
+	// rateBurst 限速器允许的短时突发条数
+	rateBurst = 3
+	// retryBaseDelay 首次重试的等待时间，之后逐次翻倍
+	retryBaseDelay = 2 * time.Second
+	// retryMaxDelay 重试等待时间上限
+	retryMaxDelay = 30 * time.Second
+	// partSuffixRunes 为分段序号“（1/3）”预留的字符数
+	partSuffixRunes = 12
+)
+
+// DefaultRetryRetCodes 视为限流或临时失败的 OneBot retcode。
+// 103 为 go-cqhttp、NapCat 等实现发送失败（常见于风控与发送过快）时的返回码
+var DefaultRetryRetCodes = []int{103}
+
+// rateLimiter 全局与按发送目标的双层限速
+type rateLimiter struct {
+	global    *rate.Limiter
+	perTarget rate.Limit
+	burst     int
+
+	mu      sync.Mutex
+	targets map[string]*rate.Limiter
+}
+
+// newRateLimiter 创建限速器，参数为每分钟消息数，小于等于 0 表示不限
+func newRateLimiter(global, perTarget int) *rateLimiter {
+	l := &rateLimiter{
+		global:    rate.NewLimiter(perMinute(global), min(max(global, 1), rateBurst)),
+		perTarget: perMinute(perTarget),
+		burst:     min(max(perTarget, 1), rateBurst),
+		targets:   make(map[string]*rate.Limiter),
+	}
+	return l
+}
+
+func perMinute(n int) rate.Limit {
+	if n <= 0 {
+		return rate.Inf
+	}
+	return rate.Limit(float64(n) / 60)
+}
+
+// wait 等待目标与全局配额，ctx 结束或等待时间超过截止时间时返回错误
+func (l *rateLimiter) wait(ctx context.Context, target string) error {
+	l.mu.Lock()
+	limiter, ok := l.targets[target]
+	if !ok {
+		limiter = rate.NewLimiter(l.perTarget, l.burst)
+		l.targets[target] = limiter
+	}
+	l.mu.Unlock()
+
+	if err := limiter.Wait(ctx); err != nil {
+		return fmt.Errorf("等待发送配额失败: %w", err)
+	}
+	if err := l.global.Wait(ctx); err != nil {
+		return fmt.Errorf("等待发送配额失败: %w", err)
+	}
+	return nil
+}
+
+// retryPolicy 发送失败时的重试策略
+type retryPolicy struct {
+	maxRetries int
+	retCodes   map[int]bool
+}
+
+func newRetryPolicy(maxRetries int, retCodes []int) retryPolicy {
+	p := retryPolicy{maxRetries: max(maxRetries, 0), retCodes: make(map[int]bool, len(retCodes))}
+	for _, code := range retCodes {
+		p.retCodes[code] = true
+	}
+	return p
+}
+
+// retryable 判断错误是否为限流或临时失败：指定的 retcode、HTTP 429 与 5xx、WebSocket 未连接
+func (p retryPolicy) retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return p.retCodes[apiErr.RetCode]
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	return errors.Is(err, ErrWSNotConnected)
+}
+
+// delay 返回第 attempt 次重试前的等待时间
+func (p retryPolicy) delay(attempt int) time.Duration {
+	d := retryBaseDelay << attempt
+	if d <= 0 || d > retryMaxDelay {
+		return retryMaxDelay
+	}
+	return d
+}
+
+// SplitText 将文本切分为不超过 limit 个字符的多段。优先在空行（课程之间）处切分，
+// 单个课程超长时按行切分，单行超长时按字符切分。分为多段时每段末尾附加“（i/n）”
+func SplitText(text string, limit int) []string {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+	limit = max(limit-partSuffixRunes, 1)
+
+	var parts []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+		}
+	}
+	appendPiece := func(piece, sep string) {
+		if current.Len() > 0 && utf8.RuneCountInString(current.String())+utf8.RuneCountInString(sep+piece) > limit {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteString(sep)
+		}
+		current.WriteString(piece)
+	}
+
+	for _, section := range strings.Split(text, "\n\n") {
+		if utf8.RuneCountInString(section) <= limit {
+			appendPiece(section, "\n\n")
+			continue
+		}
+		flush()
+		for _, line := range strings.Split(section, "\n") {
+			for _, chunk := range splitRunes(line, limit) {
+				appendPiece(chunk, "\n")
+			}
+		}
+		flush()
+	}
+	flush()
+
+	if len(parts) > 1 {
+		for i := range parts {
+			parts[i] += fmt.Sprintf("\n（%d/%d）", i+1, len(parts))
+		}
+	}
+	return parts
+}
+
+// splitRunes 按字符数切分单行文本
+func splitRunes(s string, limit int) []string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return []string{s}
+	}
+	var chunks []string
+	for len(runes) > limit {
+		chunks = append(chunks, string(runes[:limit]))
+		runes = runes[limit:]
+	}
+	return append(chunks, string(runes))
+}
+
+// deliver 限速后调用 OneBot API，限流或临时失败时按指数退避重试
+func (n *OneBotNotifier) deliver(ctx context.Context, target, action string, params any) error {
+	for attempt := 0; ; attempt++ {
+		if err := n.limiter.wait(ctx, target); err != nil {
+			return err
+		}
+		_, err := n.transport.Call(ctx, action, params)
+		if err == nil || attempt >= n.retry.maxRetries || !n.retry.retryable(err) {
+			return err
+		}
+
+		delay := n.retry.delay(attempt)
+		log.Printf("[WARN] OneBot 发送到 %s 失败，%s 后第 %d 次重试: %v", target, delay, attempt+1, err)
+		if !sleepOrDone(ctx, delay) {
+			return fmt.Errorf("%w（重试已取消: %v）", err, ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{
+			name:  "within limit",
+			text:  "aaaaa\n\nbbbbb",
+			limit: 20,
+			want:  []string{"aaaaa\n\nbbbbb"},
+		},
+		{
+			name:  "no limit",
+			text:  "aaaaa\n\nbbbbb\n\nccccc",
+			limit: 0,
+			want:  []string{"aaaaa\n\nbbbbb\n\nccccc"},
+		},
+		{
+			// 预留序号后每段 12 个字符，恰好容纳两门课程
+			name:  "split at section boundaries",
+			text:  "aaaaa\n\nbbbbb\n\nccccc\n\nddddd",
+			limit: 24,
+			want:  []string{"aaaaa\n\nbbbbb\n（1/2）", "ccccc\n\nddddd\n（2/2）"},
+		},
+		{
+			// 预留序号后每段 8 个字符，超长课程按行切分，超长行按字符切分
+			name:  "single section longer than limit",
+			text:  "ab\n\n一二三四五六七八九十甲乙丙\nxy\n\ncd",
+			limit: 20,
+			want: []string{
+				"ab\n（1/4）",
+				"一二三四五六七八\n（2/4）",
+				"九十甲乙丙\nxy\n（3/4）",
+				"cd\n（4/4）",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitText(tt.text, tt.limit)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("SplitText() = %q, want %q", got, tt.want)
+			}
+			for _, part := range got {
+				if n := utf8.RuneCountInString(part); tt.limit > 0 && n > tt.limit {
+					t.Errorf("part %q has %d runes, limit %d", part, n, tt.limit)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		retCodes []int
+		err      error
+		want     bool
+	}{
+		{"default retcode", DefaultRetryRetCodes, &APIError{RetCode: 103}, true},
+		{"other retcode", DefaultRetryRetCodes, &APIError{RetCode: 100}, false},
+		{"custom retcode", []int{1400}, &APIError{RetCode: 1400}, true},
+		{"default retcode not configured", []int{1400}, &APIError{RetCode: 103}, false},
+		{"wrapped retcode", DefaultRetryRetCodes, fmt.Errorf("发送失败: %w", &APIError{RetCode: 103}), true},
+		{"too many requests", DefaultRetryRetCodes, &StatusError{StatusCode: 429}, true},
+		{"internal server error", DefaultRetryRetCodes, &StatusError{StatusCode: 500}, true},
+		{"bad gateway", DefaultRetryRetCodes, &StatusError{StatusCode: 502}, true},
+		{"unauthorized", DefaultRetryRetCodes, &StatusError{StatusCode: 401}, false},
+		{"not found", DefaultRetryRetCodes, &StatusError{StatusCode: 404}, false},
+		{"websocket not connected", DefaultRetryRetCodes, fmt.Errorf("调用失败: %w", ErrWSNotConnected), true},
+		{"websocket closed", DefaultRetryRetCodes, ErrWSClosed, false},
+		{"context canceled", DefaultRetryRetCodes, context.Canceled, false},
+		{"other error", DefaultRetryRetCodes, errors.New("connection refused"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newRetryPolicy(DefaultMaxRetries, tt.retCodes)
+			if got := p.retryable(tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 2 * time.Second},
+		{1, 4 * time.Second},
+		{3, 16 * time.Second},
+		{4, retryMaxDelay},
+		{100, retryMaxDelay},
+	}
+	p := newRetryPolicy(-1, nil)
+	if p.maxRetries != 0 {
+		t.Errorf("maxRetries = %d, want 0", p.maxRetries)
+	}
+	for _, tt := range tests {
+		if got := p.delay(tt.attempt); got != tt.want {
+			t.Errorf("delay(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		global    int
+		perTarget int
+		targets   []string
+		want      []bool // 每次等待是否在截止时间前取得配额
+	}{
+		{
+			name:    "unlimited",
+			targets: []string{"group:1", "group:1", "group:1", "group:1", "group:1"},
+			want:    []bool{true, true, true, true, true},
+		},
+		{
+			name:      "per target burst",
+			perTarget: 2,
+			targets:   []string{"group:1", "group:1", "group:1", "private:2"},
+			want:      []bool{true, true, false, true},
+		},
+		{
+			name:    "global burst",
+			global:  2,
+			targets: []string{"group:1", "group:2", "group:3"},
+			want:    []bool{true, true, false},
+		},
+		{
+			name:      "global and per target",
+			global:    60,
+			perTarget: 2,
+			targets:   []string{"group:1", "group:1", "private:2", "group:1", "private:2"},
+			want:      []bool{true, true, true, false, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRateLimiter(tt.global, tt.perTarget)
+			for i, target := range tt.targets {
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				err := l.wait(ctx, target)
+				cancel()
+				if got := err == nil; got != tt.want[i] {
+					t.Errorf("wait #%d(%s) error = %v, want ok %v", i+1, target, err, tt.want[i])
+				}
+			}
+		})
+	}
+}
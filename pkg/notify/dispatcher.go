@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DispatchError 记录各渠道的推送错误，未出现在其中的渠道均已推送成功
+type DispatchError struct {
+	Errors map[string]error
+}
+
+func (e *DispatchError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("渠道[%s]: %v", name, e.Errors[name]))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (e *DispatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Dispatcher 将同一批事件并行推送到多个渠道
+type Dispatcher struct {
+	notifiers []Notifier
+}
+
+// NewDispatcher 创建多渠道推送器
+func NewDispatcher(notifiers ...Notifier) *Dispatcher {
+	return &Dispatcher{notifiers: append([]Notifier(nil), notifiers...)}
+}
+
+// Name 返回参与推送的渠道名称
+func (d *Dispatcher) Name() string {
+	names := make([]string, 0, len(d.notifiers))
+	for _, n := range d.notifiers {
+		names = append(names, n.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+// Notifiers 返回参与推送的全部渠道
+func (d *Dispatcher) Notifiers() []Notifier {
+	return append([]Notifier(nil), d.notifiers...)
+}
+
+// Close 关闭实现了 io.Closer 的渠道，释放连接等资源
+func (d *Dispatcher) Close() error {
+	var allErr error
+	for _, n := range d.notifiers {
+		if closer, ok := n.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				allErr = errors.Join(allErr, fmt.Errorf("渠道[%s]关闭失败: %w", n.Name(), err))
+			}
+		}
+	}
+	return allErr
+}
+
+// Send 并行推送到所有渠道，等待全部完成后返回。
+// 任一渠道失败时返回 *DispatchError，其中按渠道名称记录错误。
+func (d *Dispatcher) Send(ctx context.Context, batch Batch) error {
+	return d.send(ctx, batch, d.notifiers)
+}
+
+// SendTo 只推送到 targets 中的渠道，用于重发此前失败的渠道与目标。键为渠道名称，
+// 值为该渠道需要重发的目标（见 TargetError），为空时推送到该渠道的全部目标。
+// 错误形式与 Send 相同，不存在的渠道名称会被忽略。
+func (d *Dispatcher) SendTo(ctx context.Context, batch Batch, targets map[string][]string) error {
+	selected := make([]Notifier, 0, len(targets))
+	for _, n := range d.notifiers {
+		if channelTargets, ok := targets[n.Name()]; ok {
+			selected = append(selected, targetNotifier{n, channelTargets})
+		}
+	}
+	return d.send(ctx, batch, selected)
+}
+
+// targetNotifier 只推送到指定目标的渠道，目标为空或渠道不支持按目标推送时推送到全部目标
+type targetNotifier struct {
+	Notifier
+	targets []string
+}
+
+func (t targetNotifier) Send(ctx context.Context, batch Batch) error {
+	return sendTargets(ctx, t.Notifier, batch, t.targets)
+}
+
+// sendTargets 推送到渠道的指定目标，targets 为空时推送到全部目标
+func sendTargets(ctx context.Context, n Notifier, batch Batch, targets []string) error {
+	if sender, ok := n.(TargetSender); ok && len(targets) > 0 {
+		return sender.SendTargets(ctx, batch, targets)
+	}
+	return n.Send(ctx, batch)
+}
+
+func (d *Dispatcher) send(ctx context.Context, batch Batch, notifiers []Notifier) error {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs = make(map[string]error)
+	)
+
+	for _, n := range notifiers {
+		wg.Add(1)
+		go func(n Notifier) {
+			defer wg.Done()
+			if err := n.Send(ctx, batch); err != nil {
+				mu.Lock()
+				errs[n.Name()] = err
+				mu.Unlock()
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return &DispatchError{Errors: errs}
+	}
+	return nil
+}
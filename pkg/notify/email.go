@@ -0,0 +1,357 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP 连接加密方式
+const (
+	EmailSecurityStartTLS = "starttls" // 明文连接后升级为 TLS，通常使用 587 端口
+	EmailSecuritySSL      = "ssl"      // 直接建立 TLS 连接，通常使用 465 端口
+	EmailSecurityNone     = "none"     // 不加密，仅用于本机或内网中继
+)
+
+// emailTimeout 单次推送（连接、认证与发送全部邮件）的默认超时
+const emailTimeout = 30 * time.Second
+
+// EmailServer SMTP 服务器配置
+type EmailServer struct {
+	Host     string
+	Port     int
+	Username string // 为空时不认证
+	Password string
+	Security string // starttls / ssl / none，默认 starttls
+}
+
+// EmailRecipient 邮件收件人
+type EmailRecipient struct {
+	Address string
+	Courses []string // 只接收命中这些关键词的课程，为空时接收全部
+}
+
+// DialFunc 建立 SMTP 连接，可替换为本地 SMTP 测试桩
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// EmailNotifier 通过 SMTP 发送 HTML 与纯文本双格式邮件
+type EmailNotifier struct {
+	server     EmailServer
+	from       string
+	recipients []EmailRecipient
+	renderer   *Renderer
+	dial       DialFunc
+	tlsConfig  *tls.Config
+	timeout    time.Duration
+}
+
+// EmailOption 邮件推送器配置项
+type EmailOption func(*EmailNotifier)
+
+// WithEmailRenderer 设置消息模板，纯文本与 HTML 正文分别使用 text 与 html 模板
+func WithEmailRenderer(renderer *Renderer) EmailOption {
+	return func(n *EmailNotifier) {
+		if renderer != nil {
+			n.renderer = renderer
+		}
+	}
+}
+
+// WithEmailDialer 设置建立连接的方式，默认使用 net.Dialer
+func WithEmailDialer(dial DialFunc) EmailOption {
+	return func(n *EmailNotifier) {
+		n.dial = dial
+	}
+}
+
+// WithEmailTLSConfig 设置 TLS 配置，默认校验服务器证书并使用 Host 作为 SNI
+func WithEmailTLSConfig(cfg *tls.Config) EmailOption {
+	return func(n *EmailNotifier) {
+		n.tlsConfig = cfg
+	}
+}
+
+// WithEmailTimeout 设置单次推送的超时
+func WithEmailTimeout(timeout time.Duration) EmailOption {
+	return func(n *EmailNotifier) {
+		if timeout > 0 {
+			n.timeout = timeout
+		}
+	}
+}
+
+// NewEmailNotifier 创建邮件推送器，from 为发件人地址。
+func NewEmailNotifier(server EmailServer, from string, recipients []EmailRecipient, opts ...EmailOption) *EmailNotifier {
+	if server.Security == "" {
+		server.Security = EmailSecurityStartTLS
+	}
+	dialer := &net.Dialer{}
+	n := &EmailNotifier{
+		server:     server,
+		from:       strings.TrimSpace(from),
+		recipients: append([]EmailRecipient(nil), recipients...),
+		renderer:   DefaultRenderer(),
+		dial:       dialer.DialContext,
+		timeout:    emailTimeout,
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+// Name 返回渠道名称。
+func (n *EmailNotifier) Name() string {
+	return "email"
+}
+
+// Send 按收件人的课程列表筛选事件，每位收件人单独发送一封邮件，共用一个 SMTP 连接。
+// 部分收件人失败时返回 *TargetError，目标标识为收件人地址。
+func (n *EmailNotifier) Send(ctx context.Context, batch Batch) error {
+	return n.send(ctx, batch, nil)
+}
+
+// SendTargets 只发送给 targets 中的收件人地址
+func (n *EmailNotifier) SendTargets(ctx context.Context, batch Batch, targets []string) error {
+	only := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		only[target] = true
+	}
+	return n.send(ctx, batch, only)
+}
+
+// send 发送给 only 中的收件人，only 为 nil 时发送给全部收件人
+func (n *EmailNotifier) send(ctx context.Context, batch Batch, only map[string]bool) error {
+	type mail struct {
+		to  string
+		msg []byte
+	}
+	var mails []mail
+	for _, recipient := range n.recipients {
+		if only != nil && !only[recipient.Address] {
+			continue
+		}
+		events := recipientEvents(recipient, batch.Events)
+		if len(events) == 0 {
+			continue
+		}
+		msg, err := n.compose(recipient.Address, Batch{Round: batch.Round, Time: batch.Time, Events: events})
+		if err != nil {
+			return err
+		}
+		mails = append(mails, mail{to: recipient.Address, msg: msg})
+	}
+	if len(mails) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+	client, err := n.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var failed []string
+	var allErr error
+	for i, m := range mails {
+		if err := n.deliver(client, m.to, m.msg); err != nil {
+			failed = append(failed, m.to)
+			allErr = errors.Join(allErr, fmt.Errorf("邮件[%s]发送失败: %w", m.to, err))
+			// 清除失败的事务，继续发送其余收件人
+			if err := client.Reset(); err != nil {
+				for _, rest := range mails[i+1:] {
+					failed = append(failed, rest.to)
+				}
+				return &TargetError{Targets: failed, Err: errors.Join(allErr, fmt.Errorf("重置 SMTP 会话失败: %w", err))}
+			}
+		}
+	}
+	if len(failed) > 0 {
+		_ = client.Quit()
+		return &TargetError{Targets: failed, Err: allErr}
+	}
+	// 邮件均已被服务器接收，QUIT 失败不影响送达，不能因此重发
+	if err := client.Quit(); err != nil {
+		log.Printf("[WARN] 关闭 SMTP 会话失败: %v", err)
+	}
+	return nil
+}
+
+// recipientEvents 返回收件人关注的事件
+func recipientEvents(recipient EmailRecipient, events []Event) []Event {
+	if len(recipient.Courses) == 0 {
+		return events
+	}
+	var matched []Event
+	for _, event := range events {
+		for _, course := range recipient.Courses {
+			if event.HasKeyword(course) {
+				matched = append(matched, event)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// connect 建立 SMTP 连接，按配置加密并认证
+func (n *EmailNotifier) connect(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(n.server.Host, strconv.Itoa(n.server.Port))
+	conn, err := n.dial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("连接 SMTP 服务器失败: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if n.server.Security == EmailSecuritySSL {
+		tlsConn := tls.Client(conn, n.clientTLSConfig())
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("SMTP TLS 握手失败: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	client, err := smtp.NewClient(conn, n.server.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SMTP 握手失败: %w", err)
+	}
+	if err := n.secure(client); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// secure 按需升级 STARTTLS 并认证
+func (n *EmailNotifier) secure(client *smtp.Client) error {
+	if n.server.Security == EmailSecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("SMTP 服务器不支持 STARTTLS")
+		}
+		if err := client.StartTLS(n.clientTLSConfig()); err != nil {
+			return fmt.Errorf("SMTP STARTTLS 失败: %w", err)
+		}
+	}
+	if n.server.Username == "" {
+		return nil
+	}
+	if ok, _ := client.Extension("AUTH"); !ok {
+		return errors.New("SMTP 服务器不支持认证")
+	}
+	auth := smtp.PlainAuth("", n.server.Username, n.server.Password, n.server.Host)
+	if err := client.Auth(auth); err != nil {
+		return fmt.Errorf("SMTP 认证失败: %w", err)
+	}
+	return nil
+}
+
+func (n *EmailNotifier) clientTLSConfig() *tls.Config {
+	if n.tlsConfig != nil {
+		return n.tlsConfig
+	}
+	return &tls.Config{ServerName: n.server.Host, MinVersion: tls.VersionTLS12}
+}
+
+// deliver 在已建立的连接上发送一封邮件
+func (n *EmailNotifier) deliver(client *smtp.Client, to string, msg []byte) error {
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// compose 生成 multipart/alternative 邮件，包含纯文本与 HTML 正文
+func (n *EmailNotifier) compose(to string, batch Batch) ([]byte, error) {
+	text, err := n.renderer.Render(FormatText, batch)
+	if err != nil {
+		return nil, err
+	}
+	html, err := n.renderer.Render(FormatHTML, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", html},
+	} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	now := batch.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	subject := fmt.Sprintf("【选课监控】%d 门课程余量增加", len(batch.Events))
+
+	var msg bytes.Buffer
+	for _, header := range [][2]string{
+		{"From", n.from},
+		{"To", to},
+		{"Subject", mime.BEncoding.Encode("UTF-8", subject)},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%d@%s>", time.Now().UnixNano(), emailDomain(n.from))},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + writer.Boundary()},
+	} {
+		msg.WriteString(header[0] + ": " + header[1] + "\r\n")
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// emailDomain 返回邮件地址的域名部分，用于生成 Message-ID
+func emailDomain(address string) string {
+	if i := strings.LastIndex(address, "@"); i >= 0 && i < len(address)-1 {
+		return strings.Trim(address[i+1:], "<> ")
+	}
+	return "localhost"
+}
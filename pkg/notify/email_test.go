@@ -0,0 +1,258 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/jwxt"
+)
+
+// stubSMTP 运行在 net.Pipe 上的最小 SMTP 服务器，记录认证信息与收到的邮件
+type stubSMTP struct {
+	t        *testing.T
+	tlsCert  *tls.Certificate // 非空时支持 STARTTLS
+	reject   string           // RCPT 时拒绝的收件人
+	username string
+	password string
+
+	mu       sync.Mutex
+	auth     string
+	startTLS bool
+	messages map[string]string // 收件人 -> 邮件原文
+}
+
+func (s *stubSMTP) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, server := net.Pipe()
+	go s.serve(server)
+	return client, nil
+}
+
+func (s *stubSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	reply := func(format string, args ...any) {
+		_ = text.PrintfLine(format, args...)
+	}
+
+	reply("220 stub ESMTP")
+	var secured bool
+	var rcpts []string
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250-stub")
+			if s.tlsCert != nil && !secured {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{*s.tlsCert}})
+			if err := tlsConn.Handshake(); err != nil {
+				s.t.Errorf("TLS 握手失败: %v", err)
+				return
+			}
+			conn, text, secured = tlsConn, textproto.NewConn(tlsConn), true
+			s.mu.Lock()
+			s.startTLS = true
+			s.mu.Unlock()
+		case "AUTH":
+			mechanism, payload, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(payload)
+			s.mu.Lock()
+			s.auth = mechanism + " " + string(decoded)
+			s.mu.Unlock()
+			if string(decoded) != "\x00"+s.username+"\x00"+s.password {
+				reply("535 authentication failed")
+				continue
+			}
+			reply("235 ok")
+		case "MAIL":
+			rcpts = nil
+			reply("250 ok")
+		case "RCPT":
+			addr := strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if addr == s.reject {
+				reply("550 mailbox unavailable")
+				continue
+			}
+			rcpts = append(rcpts, addr)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			body, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			for _, rcpt := range rcpts {
+				s.messages[rcpt] = string(body)
+			}
+			s.mu.Unlock()
+			reply("250 queued")
+		case "RSET":
+			rcpts = nil
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestEmailNotifierSend(t *testing.T) {
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+
+	batch := Batch{
+		Round: "2025-2026-1 第一轮",
+		Time:  time.Date(2025, 9, 1, 9, 0, 0, 0, time.Local),
+		Events: []Event{{
+			Type:     EventSeatsIncreased,
+			Course:   jwxt.CourseInfo{Kch: "A001", Kcmc: "高等数学", Skls: "张老师", Syrs: "3"},
+			Keyword:  "A001",
+			OldSeats: 0,
+			NewSeats: 3,
+		}},
+	}
+	recipients := []EmailRecipient{
+		{Address: "a@example.com"},
+		{Address: "rejected@example.com"},
+		{Address: "c@example.com", Courses: []string{"A001"}},
+		{Address: "other@example.com", Courses: []string{"B002"}},
+	}
+
+	tests := []struct {
+		name     string
+		security string
+		host     string
+		tls      bool
+	}{
+		// PlainAuth 只允许在 TLS 或 localhost 上发送密码
+		{"none", EmailSecurityNone, "localhost", false},
+		{"starttls", EmailSecurityStartTLS, "example.com", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubSMTP{
+				t:        t,
+				reject:   "rejected@example.com",
+				username: "monitor@example.com",
+				password: "secret",
+				messages: make(map[string]string),
+			}
+			if tt.tls {
+				stub.tlsCert = &ts.TLS.Certificates[0]
+			}
+
+			n := NewEmailNotifier(EmailServer{
+				Host:     tt.host,
+				Port:     25,
+				Username: stub.username,
+				Password: stub.password,
+				Security: tt.security,
+			}, "选课监控 <monitor@example.com>", recipients,
+				WithEmailDialer(stub.dial),
+				WithEmailTLSConfig(&tls.Config{ServerName: "example.com", RootCAs: roots}),
+				WithEmailTimeout(5*time.Second),
+			)
+
+			err := n.Send(context.Background(), batch)
+			var targetErr *TargetError
+			if !errors.As(err, &targetErr) {
+				t.Fatalf("Send() error = %v, want *TargetError", err)
+			}
+			if !slices.Equal(targetErr.Targets, []string{"rejected@example.com"}) {
+				t.Errorf("failed targets = %v, want [rejected@example.com]", targetErr.Targets)
+			}
+
+			stub.mu.Lock()
+			defer stub.mu.Unlock()
+			if stub.startTLS != tt.tls {
+				t.Errorf("STARTTLS used = %v, want %v", stub.startTLS, tt.tls)
+			}
+			if want := "PLAIN \x00monitor@example.com\x00secret"; stub.auth != want {
+				t.Errorf("AUTH = %q, want %q", stub.auth, want)
+			}
+			if got := slices.Sorted(maps.Keys(stub.messages)); !slices.Equal(got, []string{"a@example.com", "c@example.com"}) {
+				t.Fatalf("delivered to %v, want [a@example.com c@example.com]", got)
+			}
+			checkEmailMessage(t, stub.messages["a@example.com"])
+		})
+	}
+}
+
+// checkEmailMessage 校验邮件头与 multipart/alternative 正文
+func checkEmailMessage(t *testing.T, raw string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(raw)))
+	if err != nil {
+		t.Fatalf("解析邮件失败: %v", err)
+	}
+
+	if id := msg.Header.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID = %q, want <...@example.com>", id)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "【选课监控】1 门课程余量增加" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q (%v), want multipart/alternative", mediaType, err)
+	}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	var types []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("读取邮件分段失败: %v", err)
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("读取邮件分段失败: %v", err)
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		types = append(types, partType)
+		if !strings.Contains(string(content), "高等数学") {
+			t.Errorf("%s 正文缺少课程名称: %q", partType, content)
+		}
+		if partType == "text/html" && !strings.Contains(string(content), "<") {
+			t.Errorf("text/html 正文不是 HTML: %q", content)
+		}
+	}
+	if !slices.Equal(types, []string{"text/plain", "text/html"}) {
+		t.Errorf("parts = %v, want [text/plain text/html]", types)
+	}
+}
@@ -0,0 +1,202 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// OneBotEvent OneBot v11 上报的事件，仅解析处理消息所需的字段
+type OneBotEvent struct {
+	PostType    string          `json:"post_type"`
+	MessageType string          `json:"message_type"` // group / private
+	SelfID      int64           `json:"self_id"`
+	UserID      int64           `json:"user_id"`
+	GroupID     int64           `json:"group_id"`
+	RawMessage  string          `json:"raw_message"`
+	Message     json.RawMessage `json:"message"`
+}
+
+// EventHandler 处理 OneBot 上报的事件。事件在独立的 goroutine 中处理，可以在其中调用 API。
+type EventHandler func(event *OneBotEvent)
+
+// EventSource 可以接收 OneBot 事件上报的组件，如 WebSocket 调用方式与 HTTP 上报服务
+type EventSource interface {
+	OnEvent(handler EventHandler)
+}
+
+// IsMessage 判断是否为群聊或私聊消息事件
+func (e *OneBotEvent) IsMessage() bool {
+	return e.PostType == "message" && (e.MessageType == "group" || e.MessageType == "private")
+}
+
+// Sender 返回发送者 QQ 号
+func (e *OneBotEvent) Sender() string {
+	return strconv.FormatInt(e.UserID, 10)
+}
+
+// Group 返回群号，私聊消息返回空字符串
+func (e *OneBotEvent) Group() string {
+	if e.MessageType != "group" {
+		return ""
+	}
+	return strconv.FormatInt(e.GroupID, 10)
+}
+
+var (
+	cqAtPattern   = regexp.MustCompile(`\[CQ:at,qq=(\w+)[^\]]*\]`)
+	cqCodePattern = regexp.MustCompile(`\[CQ:[^\]]*\]`)
+)
+
+// Text 返回消息中的纯文本，忽略 @、图片等非文本段。
+// mentionsSelf 表示消息是否 @ 了机器人自己。
+func (e *OneBotEvent) Text() (text string, mentionsSelf bool) {
+	self := strconv.FormatInt(e.SelfID, 10)
+
+	// message 为消息段数组时逐段解析，data 中的值可能是字符串或数字
+	var segments []struct {
+		Type string         `json:"type"`
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(e.Message, &segments); err == nil && len(segments) > 0 {
+		var b strings.Builder
+		for _, seg := range segments {
+			switch seg.Type {
+			case "text":
+				b.WriteString(fmt.Sprint(seg.Data["text"]))
+			case "at":
+				if fmt.Sprint(seg.Data["qq"]) == self {
+					mentionsSelf = true
+				}
+			}
+		}
+		return strings.TrimSpace(b.String()), mentionsSelf
+	}
+
+	// 否则按 CQ 码字符串解析
+	raw := e.RawMessage
+	if raw == "" {
+		_ = json.Unmarshal(e.Message, &raw)
+	}
+	for _, match := range cqAtPattern.FindAllStringSubmatch(raw, -1) {
+		if match[1] == self {
+			mentionsSelf = true
+		}
+	}
+	text = cqCodePattern.ReplaceAllString(raw, "")
+	text = strings.NewReplacer("&#91;", "[", "&#93;", "]", "&#44;", ",", "&amp;", "&").Replace(text)
+	return strings.TrimSpace(text), mentionsSelf
+}
+
+// eventDispatcher 保存事件处理函数，供 WebSocket 与 HTTP 上报共用
+type eventDispatcher struct {
+	handler atomic.Pointer[EventHandler]
+}
+
+// OnEvent 设置事件处理函数，重复调用时替换之前的处理函数
+func (d *eventDispatcher) OnEvent(handler EventHandler) {
+	d.handler.Store(&handler)
+}
+
+// dispatch 解析事件并在新的 goroutine 中处理，避免阻塞连接的读取
+func (d *eventDispatcher) dispatch(data []byte) {
+	handler := d.handler.Load()
+	if handler == nil {
+		return
+	}
+	var event OneBotEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		log.Printf("[WARN] 解析 OneBot 事件失败: %v", err)
+		return
+	}
+	if event.PostType == "" || event.PostType == "meta_event" {
+		return
+	}
+	go (*handler)(&event)
+}
+
+// EventServer 接收 OneBot HTTP POST 上报的事件，用于 HTTP 通信方式
+type EventServer struct {
+	eventDispatcher
+	secret string
+	server *http.Server
+}
+
+// NewEventServer 监听 addr 接收事件上报，secret 非空时校验 X-Signature 签名
+func NewEventServer(addr, secret string) (*EventServer, error) {
+	s := &EventServer{secret: strings.TrimSpace(secret)}
+	s.server = &http.Server{
+		Addr:              addr,
+		Handler:           http.HandlerFunc(s.serveHTTP),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("监听 OneBot 事件上报地址失败: %w", err)
+	}
+	go func() {
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[ERROR] OneBot 事件上报服务异常退出: %v", err)
+		}
+	}()
+	log.Printf("[INFO] OneBot 事件上报监听于 %s", ln.Addr())
+	return s, nil
+}
+
+func (s *EventServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if !s.verify(r.Header.Get("X-Signature"), body) {
+		log.Printf("[WARN] 拒绝签名错误的 OneBot 事件上报: %s", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.dispatch(body)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verify 校验 OneBot HTTP 上报签名: X-Signature: sha1=<HMAC-SHA1(secret, body)>
+func (s *EventServer) verify(signature string, body []byte) bool {
+	if s.secret == "" {
+		return true
+	}
+	sum, ok := strings.CutPrefix(signature, "sha1=")
+	if !ok {
+		return false
+	}
+	expected, err := hex.DecodeString(sum)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, []byte(s.secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// Close 停止接收事件上报
+func (s *EventServer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
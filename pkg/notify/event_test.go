@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sign 按 OneBot 规则计算 X-Signature
+func sign(secret, body string) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestEventServerVerify(t *testing.T) {
+	const body = `{"post_type":"message","message_type":"private","user_id":10001,"raw_message":"查询 A001"}`
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		want      bool
+	}{
+		{"valid signature", "s3cret", sign("s3cret", body), true},
+		{"wrong secret", "s3cret", sign("other", body), false},
+		{"signature of another body", "s3cret", sign("s3cret", body+" "), false},
+		{"missing prefix", "s3cret", strings.TrimPrefix(sign("s3cret", body), "sha1="), false},
+		{"uppercase hex", "s3cret", "sha1=" + strings.ToUpper(strings.TrimPrefix(sign("s3cret", body), "sha1=")), true},
+		{"invalid hex", "s3cret", "sha1=zz", false},
+		{"missing header", "s3cret", "", false},
+		{"no secret configured", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &EventServer{secret: tt.secret}
+			if got := s.verify(tt.signature, []byte(body)); got != tt.want {
+				t.Errorf("verify(%q) = %v, want %v", tt.signature, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventServerServeHTTP(t *testing.T) {
+	const body = `{"post_type":"message","message_type":"group","group_id":20002,"user_id":10001,"raw_message":"订阅 A001"}`
+	tests := []struct {
+		name      string
+		method    string
+		signature string
+		wantCode  int
+		wantEvent bool
+	}{
+		{"signed event", http.MethodPost, sign("s3cret", body), http.StatusNoContent, true},
+		{"bad signature", http.MethodPost, sign("other", body), http.StatusUnauthorized, false},
+		{"unsigned event", http.MethodPost, "", http.StatusUnauthorized, false},
+		{"wrong method", http.MethodGet, sign("s3cret", body), http.StatusMethodNotAllowed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &EventServer{secret: "s3cret"}
+			events := make(chan *OneBotEvent, 1)
+			s.OnEvent(func(event *OneBotEvent) { events <- event })
+
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(body))
+			if tt.signature != "" {
+				req.Header.Set("X-Signature", tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			s.serveHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			select {
+			case event := <-events:
+				if !tt.wantEvent {
+					t.Fatalf("签名无效的事件被分发: %+v", event)
+				}
+				if event.GroupID != 20002 {
+					t.Errorf("event = %+v", event)
+				}
+			case <-time.After(200 * time.Millisecond):
+				if tt.wantEvent {
+					t.Fatal("事件未被分发")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// fonts 目录中的第一个 .ttf / .otf / .ttc 字体会被编译进程序，用于绘制中文
+//
+//go:embed fonts
+var embeddedFonts embed.FS
+
+// ErrNoImageFont 未内置字体且未指定字体文件
+var ErrNoImageFont = errors.New("未内置中文字体，请将字体放入 pkg/notify/fonts 后重新编译，或设置 ONEBOT_IMAGE_FONT")
+
+// 图片排版参数，单位为像素
+const (
+	imageFontSize    = 20
+	imageTitleSize   = 26
+	imagePadding     = 24
+	imageCellPadding = 10
+	imageMaxColWidth = 320
+	imageMaxEvents   = 50
+)
+
+var (
+	imageBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	imageHeaderFill = color.RGBA{0xf0, 0xf2, 0xf5, 0xff}
+	imageGridColor  = color.RGBA{0xd0, 0xd4, 0xda, 0xff}
+	imageTextColor  = color.RGBA{0x1f, 0x23, 0x28, 0xff}
+	imageMutedColor = color.RGBA{0x6a, 0x73, 0x7d, 0xff}
+	imageSeatsColor = color.RGBA{0x1a, 0x7f, 0x37, 0xff}
+)
+
+// imageColumns 表格列，剩余人数列高亮显示
+var imageColumns = []string{"课程名称", "课程号", "授课教师", "上课时间", "上课地点", "剩余人数", "已选/排课", "开课单位"}
+
+const imageSeatsColumn = 5
+
+// ImageRenderer 将一批事件绘制为 PNG 表格，避免长文本消息被 QQ 折叠或拒收
+type ImageRenderer struct {
+	font *opentype.Font
+}
+
+// NewImageRenderer 创建图片渲染器。fontPath 为空时使用内置字体，未内置字体时返回 ErrNoImageFont
+func NewImageRenderer(fontPath string) (*ImageRenderer, error) {
+	var data []byte
+	var err error
+	if fontPath = strings.TrimSpace(fontPath); fontPath != "" {
+		if data, err = os.ReadFile(fontPath); err != nil {
+			return nil, fmt.Errorf("读取字体文件失败: %w", err)
+		}
+	} else if data, err = embeddedFont(); err != nil {
+		return nil, err
+	}
+
+	f, err := parseFont(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析字体失败: %w", err)
+	}
+	return &ImageRenderer{font: f}, nil
+}
+
+// embeddedFont 返回 fonts 目录中的第一个字体文件
+func embeddedFont() ([]byte, error) {
+	entries, err := fs.ReadDir(embeddedFonts, "fonts")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		switch strings.ToLower(path.Ext(entry.Name())) {
+		case ".ttf", ".otf", ".ttc":
+			return embeddedFonts.ReadFile("fonts/" + entry.Name())
+		}
+	}
+	return nil, ErrNoImageFont
+}
+
+// parseFont 解析字体，字体集合（.ttc）取其中第一个字体
+func parseFont(data []byte) (*opentype.Font, error) {
+	if !bytes.HasPrefix(data, []byte("ttcf")) {
+		return opentype.Parse(data)
+	}
+	collection, err := opentype.ParseCollection(data)
+	if err != nil {
+		return nil, err
+	}
+	return collection.Font(0)
+}
+
+// Render 将批次中的事件绘制为 PNG 表格
+func (r *ImageRenderer) Render(batch Batch) ([]byte, error) {
+	if len(batch.Events) == 0 {
+		return nil, errors.New("没有需要绘制的课程")
+	}
+	if len(batch.Events) > imageMaxEvents {
+		return nil, fmt.Errorf("课程数 %d 超过图片上限 %d", len(batch.Events), imageMaxEvents)
+	}
+
+	// opentype.Face 不支持并发使用，每次渲染单独创建
+	face, err := r.newFace(imageFontSize)
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+	titleFace, err := r.newFace(imageTitleSize)
+	if err != nil {
+		return nil, err
+	}
+	defer titleFace.Close()
+
+	title := "【选课监控】检测到课程余量增加"
+	subtitle := "检测时间：" + batch.Time.Format("2006-01-02 15:04:05")
+	if batch.Round != "" {
+		subtitle = "选课轮次：" + batch.Round + "　" + subtitle
+	}
+
+	rows := imageTableRows(batch)
+	for _, text := range append([]string{title, subtitle}, slices.Concat(rows...)...) {
+		if err := checkGlyphs(face, text); err != nil {
+			return nil, err
+		}
+	}
+
+	table := newImageTable(face, rows)
+	lineHeight := face.Metrics().Height.Ceil()
+	titleHeight := titleFace.Metrics().Height.Ceil()
+
+	tableWidth, tableHeight := table.size(lineHeight)
+	width := max(tableWidth, measure(titleFace, title), measure(face, subtitle)) + 2*imagePadding
+	height := imagePadding + titleHeight + lineHeight + imagePadding/2 + tableHeight + imagePadding
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(imageBackground), image.Point{}, draw.Src)
+
+	y := imagePadding
+	drawText(img, titleFace, imageTextColor, imagePadding, y, title)
+	y += titleHeight
+	drawText(img, face, imageMutedColor, imagePadding, y, subtitle)
+	y += lineHeight + imagePadding/2
+	table.draw(img, imagePadding, y, lineHeight)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("编码 PNG 失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *ImageRenderer) newFace(size float64) (font.Face, error) {
+	face, err := opentype.NewFace(r.font, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("创建字体失败: %w", err)
+	}
+	return face, nil
+}
+
+// imageTableRows 返回表头与每个事件的单元格文本
+func imageTableRows(batch Batch) [][]string {
+	rows := [][]string{imageColumns}
+	for _, event := range batch.Events {
+		course := event.Course
+		rows = append(rows, []string{
+			nonEmpty(course.Kcmc, "未知"),
+			nonEmpty(course.Kch, "未知"),
+			nonEmpty(course.Skls, "未知"),
+			nonEmpty(course.Sksj, "未知"),
+			nonEmpty(course.Skdd, "未知"),
+			fmt.Sprintf("%d → %d", event.OldSeats, event.NewSeats),
+			fmt.Sprintf("%d/%d", course.Xkrs, course.Pkrs),
+			nonEmpty(course.Dwmc, "未知"),
+		})
+	}
+	return rows
+}
+
+// imageTable 自动换行的表格，首行为表头
+type imageTable struct {
+	face   font.Face
+	cells  [][][]string // 行、列、折行后的各行文本
+	widths []int
+}
+
+func newImageTable(face font.Face, rows [][]string) *imageTable {
+	t := &imageTable{face: face, widths: make([]int, len(imageColumns))}
+	for _, row := range rows {
+		for col, text := range row {
+			t.widths[col] = max(t.widths[col], min(measure(face, text), imageMaxColWidth))
+		}
+	}
+	for _, row := range rows {
+		cells := make([][]string, len(row))
+		for col, text := range row {
+			cells[col] = wrapText(face, text, t.widths[col])
+		}
+		t.cells = append(t.cells, cells)
+	}
+	return t
+}
+
+func (t *imageTable) rowHeight(row, lineHeight int) int {
+	lines := 1
+	for _, cell := range t.cells[row] {
+		lines = max(lines, len(cell))
+	}
+	return lines*lineHeight + 2*imageCellPadding
+}
+
+func (t *imageTable) size(lineHeight int) (int, int) {
+	width := 1
+	for _, w := range t.widths {
+		width += w + 2*imageCellPadding + 1
+	}
+	height := 1
+	for row := range t.cells {
+		height += t.rowHeight(row, lineHeight) + 1
+	}
+	return width, height
+}
+
+func (t *imageTable) draw(img *image.RGBA, left, top, lineHeight int) {
+	width, height := t.size(lineHeight)
+	grid := image.NewUniform(imageGridColor)
+
+	y := top
+	for row, cells := range t.cells {
+		rowHeight := t.rowHeight(row, lineHeight)
+		draw.Draw(img, image.Rect(left, y, left+width, y+1), grid, image.Point{}, draw.Src)
+		if row == 0 {
+			draw.Draw(img, image.Rect(left+1, y+1, left+width-1, y+1+rowHeight), image.NewUniform(imageHeaderFill), image.Point{}, draw.Src)
+		}
+
+		x := left + 1
+		for col, lines := range cells {
+			textColor := imageTextColor
+			if row > 0 && col == imageSeatsColumn {
+				textColor = imageSeatsColor
+			}
+			for i, line := range lines {
+				drawText(img, t.face, textColor, x+imageCellPadding, y+1+imageCellPadding+i*lineHeight, line)
+			}
+			x += t.widths[col] + 2*imageCellPadding + 1
+		}
+		y += rowHeight + 1
+	}
+	draw.Draw(img, image.Rect(left, y, left+width, y+1), grid, image.Point{}, draw.Src)
+
+	x := left
+	draw.Draw(img, image.Rect(x, top, x+1, top+height), grid, image.Point{}, draw.Src)
+	for _, w := range t.widths {
+		x += w + 2*imageCellPadding + 1
+		draw.Draw(img, image.Rect(x, top, x+1, top+height), grid, image.Point{}, draw.Src)
+	}
+}
+
+// wrapText 按像素宽度逐字折行，换行符处强制折行
+func wrapText(face font.Face, text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		var line []rune
+		for _, r := range paragraph {
+			if len(line) > 0 && measure(face, string(append(line, r))) > width {
+				lines = append(lines, string(line))
+				line = line[:0]
+			}
+			line = append(line, r)
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// checkGlyphs 字体缺少字形时返回错误，避免推送无法辨认的图片
+func checkGlyphs(face font.Face, text string) error {
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		if _, ok := face.GlyphAdvance(r); !ok {
+			return fmt.Errorf("字体缺少字符 %q", r)
+		}
+	}
+	return nil
+}
+
+func measure(face font.Face, text string) int {
+	return font.MeasureString(face, text).Ceil()
+}
+
+// drawText 以 (x, y) 为文本行左上角绘制单行文本
+func drawText(img *image.RGBA, face font.Face, c color.Color, x, y int, text string) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y+face.Metrics().Ascent.Ceil()),
+	}
+	d.DrawString(text)
+}
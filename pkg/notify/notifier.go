@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/jwxt"
+)
+
+// EventType 推送事件类型
+type EventType string
+
+const (
+	// EventSeatsIncreased 课程剩余人数增加
+	EventSeatsIncreased EventType = "seats_increased"
+)
+
+// Label 返回事件类型的中文名称
+func (t EventType) Label() string {
+	switch t {
+	case EventSeatsIncreased:
+		return "余量增加"
+	default:
+		return string(t)
+	}
+}
+
+// Event 单个课程的变化事件
+type Event struct {
+	Type     EventType
+	Course   jwxt.CourseInfo
+	Keyword  string   // 命中的监控关键词（COURSE_LIST 或订阅中靠前的一项）
+	Keywords []string // 命中的全部监控关键词，用于按订阅分发
+	Module   string   // 课程所在的选课模块标识
+	Round    string   // 选课轮次 ID，未进入轮次时为空
+	OldSeats int      // 上一轮的剩余人数
+	NewSeats int      // 本轮的剩余人数
+}
+
+// Batch 一轮监控产生的全部事件
+type Batch struct {
+	Round  string
+	Time   time.Time
+	Events []Event
+}
+
+// Courses 返回批次中事件对应的课程列表，顺序与事件一致
+func (b Batch) Courses() []jwxt.CourseInfo {
+	courses := make([]jwxt.CourseInfo, 0, len(b.Events))
+	for _, event := range b.Events {
+		courses = append(courses, event.Course)
+	}
+	return courses
+}
+
+// MatchedKeywords 返回事件命中的全部关键词
+func (e Event) MatchedKeywords() []string {
+	if len(e.Keywords) == 0 && e.Keyword != "" {
+		return []string{e.Keyword}
+	}
+	return e.Keywords
+}
+
+// HasKeyword 判断事件是否命中指定关键词
+func (e Event) HasKeyword(keyword string) bool {
+	for _, kw := range e.MatchedKeywords() {
+		if kw == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+// Route 一组事件的定向推送目标
+type Route struct {
+	GroupID  string   // 群号，为空时私聊推送给 UserID
+	UserID   string   // 私聊 QQ 号
+	Mentions []string // 群消息中 @ 的成员
+	Events   []Event
+}
+
+// Router 按事件计算定向推送目标，如用户订阅
+type Router interface {
+	Routes(batch Batch) []Route
+}
+
+// Notifier 推送渠道。实现需支持并发调用，并在 ctx 取消时尽快返回。
+type Notifier interface {
+	// Name 返回渠道名称，用于日志与按渠道统计错误
+	Name() string
+	// Send 推送一批事件
+	Send(ctx context.Context, batch Batch) error
+}
+
+// TargetSender 可以只推送到部分目标（群、私聊或收件人）的渠道，用于重发此前失败的目标
+type TargetSender interface {
+	Notifier
+	// SendTargets 只推送到 targets 中的目标，目标标识与 TargetError 中的一致
+	SendTargets(ctx context.Context, batch Batch, targets []string) error
+}
+
+// TargetError 渠道内部分目标推送失败，Targets 为失败目标的标识，其余目标均已送达
+type TargetError struct {
+	Targets []string
+	Err     error
+}
+
+func (e *TargetError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TargetError) Unwrap() error {
+	return e.Err
+}
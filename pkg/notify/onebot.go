@@ -1,128 +1,362 @@
 package notify
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"net/http"
+	"log"
 	"strconv"
 	"strings"
-
-	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/jwxt"
 )
 
 type sendGroupMsgRequest struct {
-	GroupID int64  `json:"group_id"`
-	Message string `json:"message"`
+	GroupID int64   `json:"group_id"`
+	Message Message `json:"message"`
 }
 
-type onebotResponse struct {
-	Status  string `json:"status"`
-	RetCode int    `json:"retcode"`
-	Message string `json:"message"`
+type sendPrivateMsgRequest struct {
+	UserID  int64   `json:"user_id"`
+	Message Message `json:"message"`
 }
 
-// Notifier 封装 OneBot HTTP 推送能力。
-type Notifier struct {
-	oneBotURL   string
-	oneBotToken string
+// OneBotNotifier 封装 OneBot 推送能力，API 调用方式由 OneBotTransport 决定。
+type OneBotNotifier struct {
+	transport   OneBotTransport
 	groupList   []string
-	client      *http.Client
+	privateList []string
+	watchers    map[string][]string
+	// broadcastCourses 非空时只把命中这些关键词的事件广播到 groupList 与 privateList
+	broadcastCourses map[string]bool
+	router           Router
+	renderer         *Renderer
+	imageRenderer    *ImageRenderer
+
+	maxLength int
+	limiter   *rateLimiter
+	retry     retryPolicy
 }
 
-// NewNotifier 创建 OneBot 推送器。
-func NewNotifier(oneBotURL, oneBotToken string, groupList []string, client *http.Client) *Notifier {
-	return &Notifier{
-		oneBotURL:   strings.TrimRight(strings.TrimSpace(oneBotURL), "/"),
-		oneBotToken: strings.TrimSpace(oneBotToken),
-		groupList:   append([]string(nil), groupList...),
-		client:      client,
+// OneBotOption OneBot 推送器配置项
+type OneBotOption func(*OneBotNotifier)
+
+// WithPrivateList 设置接收私聊推送的 QQ 号
+func WithPrivateList(userList []string) OneBotOption {
+	return func(n *OneBotNotifier) {
+		n.privateList = append([]string(nil), userList...)
 	}
 }
 
-// SendGroupMessage 发送单条群消息。
-func (n *Notifier) SendGroupMessage(groupID string, message string) error {
-	gid, err := strconv.ParseInt(strings.TrimSpace(groupID), 10, 64)
-	if err != nil {
-		return fmt.Errorf("群号格式错误[%s]: %w", groupID, err)
+// WithCourseWatchers 设置各监控课程的关注者，推送群消息时 @ 命中课程的关注者。
+// 键为 COURSE_LIST 中的课程号，对应 Event.Keyword
+func WithCourseWatchers(watchers map[string][]string) OneBotOption {
+	return func(n *OneBotNotifier) {
+		n.watchers = make(map[string][]string, len(watchers))
+		for course, users := range watchers {
+			n.watchers[course] = append([]string(nil), users...)
+		}
 	}
+}
 
-	payload := sendGroupMsgRequest{
-		GroupID: gid,
-		Message: message,
+// WithBroadcastCourses 限定广播到群与私聊列表的课程，其余事件只按 Router 定向推送。
+// 未设置时广播全部事件
+func WithBroadcastCourses(courses []string) OneBotOption {
+	return func(n *OneBotNotifier) {
+		n.broadcastCourses = make(map[string]bool, len(courses))
+		for _, course := range courses {
+			n.broadcastCourses[course] = true
+		}
 	}
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("序列化 OneBot 请求失败: %w", err)
+}
+
+// WithRouter 设置定向推送，每个 Route 只收到与其相关的事件
+func WithRouter(router Router) OneBotOption {
+	return func(n *OneBotNotifier) {
+		n.router = router
 	}
+}
 
-	req, err := http.NewRequest(http.MethodPost, n.oneBotURL+"/send_group_msg", bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("创建 OneBot 请求失败: %w", err)
+// WithRenderer 设置消息模板，未设置时使用内置模板
+func WithRenderer(renderer *Renderer) OneBotOption {
+	return func(n *OneBotNotifier) {
+		if renderer != nil {
+			n.renderer = renderer
+		}
 	}
-	req.Header.Set("Content-Type", "application/json")
-	if n.oneBotToken != "" {
-		req.Header.Set("Authorization", "Bearer "+n.oneBotToken)
+}
+
+// WithImageRenderer 将课程变化绘制为图片推送，绘制失败时退回文本
+func WithImageRenderer(renderer *ImageRenderer) OneBotOption {
+	return func(n *OneBotNotifier) {
+		n.imageRenderer = renderer
 	}
+}
 
-	resp, err := n.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("请求 OneBot 失败: %w", err)
+// WithMaxMessageLength 设置单条文本消息的最大字符数，超出时按课程分段发送，0 表示不分段
+func WithMaxMessageLength(length int) OneBotOption {
+	return func(n *OneBotNotifier) {
+		n.maxLength = max(length, 0)
 	}
-	defer resp.Body.Close()
+}
+
+// WithRateLimit 设置每分钟最多发送的消息数：global 为全部目标合计，perTarget 为每个群或私聊，
+// 小于等于 0 表示不限
+func WithRateLimit(global, perTarget int) OneBotOption {
+	return func(n *OneBotNotifier) {
+		n.limiter = newRateLimiter(global, perTarget)
+	}
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("OneBot 响应异常: %d", resp.StatusCode)
+// WithRetry 设置限流或临时失败时的最大重试次数，以及视为限流或临时失败的 retcode。
+// HTTP 429、5xx 与 WebSocket 未连接始终重试
+func WithRetry(maxRetries int, retCodes []int) OneBotOption {
+	return func(n *OneBotNotifier) {
+		n.retry = newRetryPolicy(maxRetries, retCodes)
 	}
+}
 
-	var result onebotResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil
+// NewOneBotNotifier 创建 OneBot 推送器。
+func NewOneBotNotifier(transport OneBotTransport, groupList []string, opts ...OneBotOption) *OneBotNotifier {
+	n := &OneBotNotifier{
+		transport: transport,
+		groupList: append([]string(nil), groupList...),
+		renderer:  DefaultRenderer(),
+		maxLength: DefaultMaxMessageLength,
+		limiter:   newRateLimiter(DefaultRateLimit, DefaultTargetRateLimit),
+		retry:     newRetryPolicy(DefaultMaxRetries, DefaultRetryRetCodes),
 	}
-	if result.RetCode != 0 && result.Status != "ok" {
-		return fmt.Errorf("OneBot 返回失败: retcode=%d, message=%s", result.RetCode, result.Message)
+	for _, opt := range opts {
+		opt(n)
 	}
-	return nil
+	return n
 }
 
-// BroadcastMessage 向配置中的所有群发送同一条消息。
-func (n *Notifier) BroadcastMessage(message string) error {
+// Name 返回渠道名称。
+func (n *OneBotNotifier) Name() string {
+	return "onebot"
+}
+
+// Send 按纯文本模板（或图片）渲染事件，推送到所有群与私聊用户，并按 Router 定向推送。
+// 过长的文本按课程分段发送，群消息的第一段开头 @ 本批课程的关注者。
+// 部分群或私聊失败时返回 *TargetError，其中记录失败的目标。
+func (n *OneBotNotifier) Send(ctx context.Context, batch Batch) error {
+	return n.send(ctx, batch, nil)
+}
+
+// SendTargets 只推送到指定目标，目标标识见 TargetError
+func (n *OneBotNotifier) SendTargets(ctx context.Context, batch Batch, targets []string) error {
+	only := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		only[target] = true
+	}
+	return n.send(ctx, batch, only)
+}
+
+// send 推送到 only 中的目标，only 为 nil 时推送到全部目标
+func (n *OneBotNotifier) send(ctx context.Context, batch Batch, only map[string]bool) error {
+	var failed []string
 	var allErr error
-	for _, groupID := range n.groupList {
-		if err := n.SendGroupMessage(groupID, message); err != nil {
-			allErr = errors.Join(allErr, fmt.Errorf("群[%s]发送失败: %w", groupID, err))
+	fail := func(target string, err error) {
+		failed = append(failed, target)
+		allErr = errors.Join(allErr, err)
+	}
+
+	groups := selectTargets(n.groupList, groupTarget, only)
+	privates := selectTargets(n.privateList, privateTarget, only)
+	if broadcast := n.broadcastBatch(batch); len(broadcast.Events) > 0 && len(groups)+len(privates) > 0 {
+		parts, err := n.contents(broadcast)
+		if err != nil {
+			return err
+		}
+		mentions := n.mentions(broadcast)
+		for _, groupID := range groups {
+			if err := n.sendParts(ctx, groupID, "", mentions, parts); err != nil {
+				fail(groupTarget(groupID), fmt.Errorf("群[%s]发送失败: %w", groupID, err))
+			}
+		}
+		for _, userID := range privates {
+			if err := n.sendParts(ctx, "", userID, nil, parts); err != nil {
+				fail(privateTarget(userID), fmt.Errorf("私聊[%s]发送失败: %w", userID, err))
+			}
+		}
+	}
+
+	if n.router != nil {
+		for _, route := range n.router.Routes(batch) {
+			target := routeTarget(route)
+			if only != nil && !only[target] {
+				continue
+			}
+			if err := n.sendRoute(ctx, batch, route); err != nil {
+				fail(target, err)
+			}
 		}
 	}
-	return allErr
+
+	if len(failed) > 0 {
+		return &TargetError{Targets: failed, Err: allErr}
+	}
+	return nil
+}
+
+// 推送目标标识，用于记录与重发失败的目标
+func groupTarget(groupID string) string {
+	return "group:" + groupID
+}
+
+func privateTarget(userID string) string {
+	return "private:" + userID
 }
 
-// FormatCoursesMessage 将余量增加课程列表格式化为推送文本。
-func FormatCoursesMessage(courses []jwxt.CourseInfo) string {
-	if len(courses) == 0 {
-		return "【选课监控】本轮没有余量增加的课程。"
+func routeTarget(route Route) string {
+	if route.GroupID == "" {
+		return "route:private:" + route.UserID
 	}
+	return "route:group:" + route.GroupID
+}
 
-	var b strings.Builder
-	b.WriteString("【选课监控】检测到课程余量增加！\n")
-	for i, course := range courses {
+// selectTargets 返回 ids 中属于 only 的部分，only 为 nil 时返回全部
+func selectTargets(ids []string, target func(string) string, only map[string]bool) []string {
+	if only == nil {
+		return ids
+	}
+	var selected []string
+	for _, id := range ids {
+		if only[target(id)] {
+			selected = append(selected, id)
+		}
+	}
+	return selected
+}
+
+// broadcastBatch 返回需要广播的事件
+func (n *OneBotNotifier) broadcastBatch(batch Batch) Batch {
+	if n.broadcastCourses == nil {
+		return batch
+	}
+	filtered := batch
+	filtered.Events = nil
+	for _, event := range batch.Events {
+		for course := range n.broadcastCourses {
+			if event.HasKeyword(course) {
+				filtered.Events = append(filtered.Events, event)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// sendRoute 推送一条定向消息
+func (n *OneBotNotifier) sendRoute(ctx context.Context, batch Batch, route Route) error {
+	parts, err := n.contents(Batch{Round: batch.Round, Time: batch.Time, Events: route.Events})
+	if err != nil {
+		return err
+	}
+	if err := n.sendParts(ctx, route.GroupID, route.UserID, route.Mentions, parts); err != nil {
+		if route.GroupID == "" {
+			return fmt.Errorf("私聊[%s]定向发送失败: %w", route.UserID, err)
+		}
+		return fmt.Errorf("群[%s]定向发送失败: %w", route.GroupID, err)
+	}
+	return nil
+}
+
+// sendParts 向一个群（groupID 非空时）或私聊依次发送各段消息，群消息的第一段开头 @ mentions，
+// 任一段失败即停止
+func (n *OneBotNotifier) sendParts(ctx context.Context, groupID, userID string, mentions []string, parts []Message) error {
+	for i, part := range parts {
+		if groupID == "" {
+			if err := n.SendPrivateMessage(ctx, userID, part); err != nil {
+				return err
+			}
+			continue
+		}
 		if i > 0 {
-			b.WriteString("\n")
+			mentions = nil
+		}
+		if err := n.SendGroupMessage(ctx, groupID, mentionMessage(mentions, part)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contents 渲染推送内容：启用图片时为一张 PNG 表格，绘制失败或未启用时为纯文本模板，
+// 文本过长时按课程切分为多条消息
+func (n *OneBotNotifier) contents(batch Batch) ([]Message, error) {
+	if n.imageRenderer != nil {
+		data, err := n.imageRenderer.Render(batch)
+		if err == nil {
+			return []Message{{ImageSegment(data)}}, nil
+		}
+		log.Printf("[WARN] 绘制课程图片失败，改为发送文本: %v", err)
+	}
+	text, err := n.renderer.Render(FormatText, batch)
+	if err != nil {
+		return nil, err
+	}
+	var parts []Message
+	for _, part := range SplitText(text, n.maxLength) {
+		parts = append(parts, TextMessage(part))
+	}
+	return parts, nil
+}
+
+// mentionMessage 构造开头 @ 指定成员的消息
+func mentionMessage(users []string, content Message) Message {
+	message := Message{}
+	for _, user := range users {
+		message = append(message, AtSegment(user))
+	}
+	if len(message) > 0 {
+		message = append(message, TextSegment("\n"))
+	}
+	return append(message, content...)
+}
+
+// mentions 返回批次中各课程关注者的 QQ 号，按事件顺序去重
+func (n *OneBotNotifier) mentions(batch Batch) []string {
+	seen := make(map[string]bool)
+	var users []string
+	for _, event := range batch.Events {
+		for _, keyword := range event.MatchedKeywords() {
+			for _, user := range n.watchers[keyword] {
+				if !seen[user] {
+					seen[user] = true
+					users = append(users, user)
+				}
+			}
 		}
-		b.WriteString("━━━━━━━━━━━━━━━━\n")
-		b.WriteString(fmt.Sprintf("课程名称：%s\n", nonEmpty(course.Kcmc, "未知")))
-		b.WriteString(fmt.Sprintf("课程号：%s\n", nonEmpty(course.Kch, "未知")))
-		b.WriteString(fmt.Sprintf("授课教师：%s\n", nonEmpty(course.Skls, "未知")))
-		b.WriteString(fmt.Sprintf("上课时间：%s\n", nonEmpty(course.Sksj, "未知")))
-		b.WriteString(fmt.Sprintf("上课地点：%s\n", nonEmpty(course.Skdd, "未知")))
-		b.WriteString(fmt.Sprintf("剩余人数：%s\n", nonEmpty(course.Syrs, "未知")))
-		b.WriteString(fmt.Sprintf("已选/排课：%d/%d\n", course.Xkrs, course.Pkrs))
-		b.WriteString(fmt.Sprintf("开课单位：%s\n", nonEmpty(course.Dwmc, "未知")))
-	}
-	b.WriteString("━━━━━━━━━━━━━━━━\n")
-	b.WriteString("选课当天有事冲突需要帮抢可找他->1087476180")
-
-	return b.String()
+	}
+	return users
+}
+
+// SendGroupMessage 发送单条群消息，受限速与重试策略约束。
+func (n *OneBotNotifier) SendGroupMessage(ctx context.Context, groupID string, message Message) error {
+	gid, err := strconv.ParseInt(strings.TrimSpace(groupID), 10, 64)
+	if err != nil {
+		return fmt.Errorf("群号格式错误[%s]: %w", groupID, err)
+	}
+	return n.deliver(ctx, "群 "+strconv.FormatInt(gid, 10), "send_group_msg", sendGroupMsgRequest{GroupID: gid, Message: message})
+}
+
+// SendPrivateMessage 发送单条私聊消息，受限速与重试策略约束。
+func (n *OneBotNotifier) SendPrivateMessage(ctx context.Context, userID string, message Message) error {
+	uid, err := strconv.ParseInt(strings.TrimSpace(userID), 10, 64)
+	if err != nil {
+		return fmt.Errorf("QQ 号格式错误[%s]: %w", userID, err)
+	}
+	return n.deliver(ctx, "私聊 "+strconv.FormatInt(uid, 10), "send_private_msg", sendPrivateMsgRequest{UserID: uid, Message: message})
+}
+
+// Transport 返回 OneBot API 调用方式
+func (n *OneBotNotifier) Transport() OneBotTransport {
+	return n.transport
+}
+
+// Close 关闭 OneBot 连接。
+func (n *OneBotNotifier) Close() error {
+	return n.transport.Close()
 }
 
 func nonEmpty(value, fallback string) string {
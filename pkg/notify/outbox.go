@@ -0,0 +1,231 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OutboxFileName 待推送事件的存储文件名，存放于账号数据目录
+const OutboxFileName = "outbox.json"
+
+// OutboxEntry 一批尚未送达全部渠道的事件
+type OutboxEntry struct {
+	ID        string              `json:"id"`
+	Batch     Batch               `json:"batch"`
+	Pending   map[string][]string `json:"pending"` // 尚未送达的渠道及其中失败的目标，目标为空表示全部目标
+	CreatedAt time.Time           `json:"created_at"`
+	Attempts  int                 `json:"attempts"`
+	LastError string              `json:"last_error,omitempty"`
+}
+
+// Outbox 持久化待推送的事件。事件先写入 Outbox 再推进快照，
+// 推送失败的渠道在之后的轮次中重试，且只重发给其中失败的群、私聊或收件人，
+// 全部目标确认送达后才移除。
+type Outbox struct {
+	path string
+
+	mu      sync.Mutex
+	entries []OutboxEntry
+	seq     int
+}
+
+// channelSender 可以只推送到部分渠道与目标的 Notifier，由 Dispatcher 实现
+type channelSender interface {
+	Notifiers() []Notifier
+	SendTo(ctx context.Context, batch Batch, targets map[string][]string) error
+}
+
+// Channels 返回 Notifier 包含的渠道名称，Dispatcher 返回其中各渠道的名称
+func Channels(n Notifier) []string {
+	sender, ok := n.(channelSender)
+	if !ok {
+		return []string{n.Name()}
+	}
+	var names []string
+	for _, child := range sender.Notifiers() {
+		names = append(names, child.Name())
+	}
+	return names
+}
+
+// OpenOutbox 打开 Outbox 文件，文件不存在时创建空的 Outbox
+func OpenOutbox(path string) (*Outbox, error) {
+	o := &Outbox{path: path}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return o, nil
+		}
+		return nil, fmt.Errorf("读取 outbox 失败: %w", err)
+	}
+
+	var data struct {
+		Entries []OutboxEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("解析 outbox 失败: %w", err)
+	}
+	o.entries = data.Entries
+	return o, nil
+}
+
+// Add 写入一批待推送到 channels 的事件
+func (o *Outbox) Add(batch Batch, channels []string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.seq++
+	now := time.Now()
+	pending := make(map[string][]string, len(channels))
+	for _, channel := range channels {
+		pending[channel] = nil
+	}
+	o.entries = append(o.entries, OutboxEntry{
+		ID:        strconv.FormatInt(now.UnixNano(), 10) + "-" + strconv.Itoa(o.seq),
+		Batch:     batch,
+		Pending:   pending,
+		CreatedAt: now,
+	})
+	return o.saveLocked()
+}
+
+// Len 返回尚未送达的批次数
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+// Deliver 按写入顺序推送全部待推送批次，每批只推送到尚未送达的渠道与目标。
+// 超过 maxAge 的批次直接丢弃（maxAge 为 0 时不限），渠道已不存在的批次同样丢弃。
+// 返回本次推送中各批次的错误；推送期间不持有锁，调用方需保证同一时间只有一个 Deliver。
+func (o *Outbox) Deliver(ctx context.Context, n Notifier, maxAge time.Duration) error {
+	o.mu.Lock()
+	entries := slices.Clone(o.entries)
+	o.mu.Unlock()
+	if len(entries) == 0 {
+		return nil
+	}
+
+	channels := Channels(n)
+	results := make(map[string]*OutboxEntry, len(entries))
+	var allErr error
+	for _, entry := range entries {
+		entry.Pending = maps.Clone(entry.Pending)
+		maps.DeleteFunc(entry.Pending, func(name string, _ []string) bool {
+			return !slices.Contains(channels, name)
+		})
+		switch {
+		case maxAge > 0 && time.Since(entry.CreatedAt) > maxAge:
+			log.Printf("[WARN] 推送超过 %s 仍未送达渠道 %v，已丢弃: 课程=%d", maxAge, slices.Sorted(maps.Keys(entry.Pending)), len(entry.Batch.Events))
+			results[entry.ID] = nil
+			continue
+		case len(entry.Pending) == 0:
+			log.Printf("[WARN] 待推送批次的渠道均已不再启用，已丢弃: 课程=%d", len(entry.Batch.Events))
+			results[entry.ID] = nil
+			continue
+		}
+
+		entry.Attempts++
+		err := sendPending(ctx, n, entry)
+		if err == nil {
+			if entry.Attempts > 1 {
+				log.Printf("[INFO] 此前失败的推送已送达: 课程=%d, 尝试次数=%d", len(entry.Batch.Events), entry.Attempts)
+			}
+			results[entry.ID] = nil
+			continue
+		}
+
+		entry.Pending = stillPending(entry.Pending, err)
+		entry.LastError = err.Error()
+		results[entry.ID] = &entry
+		allErr = errors.Join(allErr, err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	kept := o.entries[:0]
+	for _, entry := range o.entries {
+		result, handled := results[entry.ID]
+		switch {
+		case !handled:
+			kept = append(kept, entry) // 推送期间新写入的批次
+		case result != nil:
+			kept = append(kept, *result)
+		}
+	}
+	o.entries = kept
+	if err := o.saveLocked(); err != nil {
+		allErr = errors.Join(allErr, err)
+	}
+	return allErr
+}
+
+// sendPending 推送到批次中尚未送达的渠道与目标
+func sendPending(ctx context.Context, n Notifier, entry OutboxEntry) error {
+	if sender, ok := n.(channelSender); ok {
+		return sender.SendTo(ctx, entry.Batch, entry.Pending)
+	}
+	return sendTargets(ctx, n, entry.Batch, entry.Pending[n.Name()])
+}
+
+// stillPending 根据推送错误计算仍未送达的渠道与目标：未出现在 DispatchError 中的渠道已送达，
+// 返回 TargetError 的渠道只保留其中失败的目标，其余错误保留该渠道原先待推送的目标
+func stillPending(pending map[string][]string, err error) map[string][]string {
+	errs := map[string]error{}
+	var dispatchErr *DispatchError
+	if errors.As(err, &dispatchErr) {
+		errs = dispatchErr.Errors
+	} else {
+		for name := range pending {
+			errs[name] = err
+		}
+	}
+
+	remaining := make(map[string][]string, len(errs))
+	for name, channelErr := range errs {
+		targets, ok := pending[name]
+		if !ok {
+			continue
+		}
+		var targetErr *TargetError
+		if errors.As(channelErr, &targetErr) {
+			targets = slices.Clone(targetErr.Targets)
+		}
+		remaining[name] = targets
+	}
+	return remaining
+}
+
+// saveLocked 原子写入 Outbox 文件，调用方需持有锁
+func (o *Outbox) saveLocked() error {
+	content, err := json.MarshalIndent(struct {
+		Entries []OutboxEntry `json:"entries"`
+	}{o.entries}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 outbox 失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(o.path), 0o700); err != nil {
+		return fmt.Errorf("创建 outbox 目录失败: %w", err)
+	}
+
+	tmpPath := o.path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0o600); err != nil {
+		return fmt.Errorf("写入 outbox 失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, o.path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("替换 outbox 文件失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeChannel 记录推送目标的渠道，failing 中的目标推送失败并返回 TargetError
+type fakeChannel struct {
+	name    string
+	targets []string
+	err     error // 非空时整个渠道推送失败
+
+	mu      sync.Mutex
+	failing map[string]bool
+	calls   [][]string // 每次推送的目标，nil 表示推送到全部目标
+}
+
+func (c *fakeChannel) Name() string { return c.name }
+
+func (c *fakeChannel) Send(ctx context.Context, batch Batch) error {
+	return c.send(nil)
+}
+
+func (c *fakeChannel) SendTargets(ctx context.Context, batch Batch, targets []string) error {
+	return c.send(targets)
+}
+
+func (c *fakeChannel) send(targets []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, targets)
+	if c.err != nil {
+		return c.err
+	}
+	if targets == nil {
+		targets = c.targets
+	}
+	var failed []string
+	for _, target := range targets {
+		if c.failing[target] {
+			failed = append(failed, target)
+		}
+	}
+	if len(failed) > 0 {
+		return &TargetError{Targets: failed, Err: errors.New("部分目标推送失败")}
+	}
+	return nil
+}
+
+// setFailing 设置推送失败的目标
+func (c *fakeChannel) setFailing(targets ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failing = make(map[string]bool)
+	for _, target := range targets {
+		c.failing[target] = true
+	}
+}
+
+// takeCalls 返回并清空推送记录
+func (c *fakeChannel) takeCalls() [][]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	calls := c.calls
+	c.calls = nil
+	return calls
+}
+
+// testBatch 返回包含一门课程的测试批次
+func testBatch() Batch {
+	return Batch{Round: "R001", Time: time.Now(), Events: []Event{{Type: EventSeatsIncreased, Keyword: "A001"}}}
+}
+
+func TestStillPending(t *testing.T) {
+	partial := &TargetError{Targets: []string{"group:2", "route:private:3"}, Err: errors.New("发送失败")}
+	tests := []struct {
+		name    string
+		pending map[string][]string
+		err     error
+		want    map[string][]string
+	}{
+		{
+			name:    "failed channel keeps all targets",
+			pending: map[string][]string{"onebot": nil, "email": nil},
+			err:     &DispatchError{Errors: map[string]error{"email": errors.New("SMTP 连接失败")}},
+			want:    map[string][]string{"email": nil},
+		},
+		{
+			name:    "target error keeps failed targets",
+			pending: map[string][]string{"onebot": nil, "email": nil},
+			err:     &DispatchError{Errors: map[string]error{"onebot": partial}},
+			want:    map[string][]string{"onebot": {"group:2", "route:private:3"}},
+		},
+		{
+			name:    "wrapped target error",
+			pending: map[string][]string{"onebot": {"group:1", "group:2"}},
+			err:     &DispatchError{Errors: map[string]error{"onebot": errors.Join(errors.New("重试已取消"), &TargetError{Targets: []string{"group:2"}, Err: errors.New("发送失败")})}},
+			want:    map[string][]string{"onebot": {"group:2"}},
+		},
+		{
+			name:    "failed retry keeps previous targets",
+			pending: map[string][]string{"email": {"a@example.com"}},
+			err:     &DispatchError{Errors: map[string]error{"email": errors.New("SMTP 连接失败")}},
+			want:    map[string][]string{"email": {"a@example.com"}},
+		},
+		{
+			name:    "error from channel not pending",
+			pending: map[string][]string{"email": nil},
+			err:     &DispatchError{Errors: map[string]error{"onebot": errors.New("发送失败")}},
+			want:    map[string][]string{},
+		},
+		{
+			name:    "plain error applies to all channels",
+			pending: map[string][]string{"onebot": {"group:1"}, "email": nil},
+			err:     errors.New("context canceled"),
+			want:    map[string][]string{"onebot": {"group:1"}, "email": nil},
+		},
+		{
+			name:    "plain target error",
+			pending: map[string][]string{"onebot": nil},
+			err:     partial,
+			want:    map[string][]string{"onebot": {"group:2", "route:private:3"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stillPending(tt.pending, tt.err)
+			if !maps.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("stillPending() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutboxDeliver(t *testing.T) {
+	onebot := &fakeChannel{name: "onebot", targets: []string{"group:1", "group:2", "route:private:3"}}
+	email := &fakeChannel{name: "email", targets: []string{"a@example.com", "b@example.com"}}
+	dispatcher := NewDispatcher(onebot, email)
+	path := filepath.Join(t.TempDir(), OutboxFileName)
+	ctx := context.Background()
+
+	outbox, err := OpenOutbox(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.Add(testBatch(), Channels(dispatcher)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一轮: QQ 群 2 与邮件收件人 b 推送失败
+	onebot.setFailing("group:2")
+	email.setFailing("b@example.com")
+	if err := outbox.Deliver(ctx, dispatcher, 0); err == nil {
+		t.Fatal("Deliver() error = nil, want target errors")
+	}
+	if calls := onebot.takeCalls(); !slices.EqualFunc(calls, [][]string{nil}, slices.Equal) {
+		t.Errorf("onebot calls = %v, want one send to all targets", calls)
+	}
+	email.takeCalls()
+
+	// 重新打开后仍保留失败的目标
+	outbox, err = OpenOutbox(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{"onebot": {"group:2"}, "email": {"b@example.com"}}
+	if outbox.Len() != 1 || !maps.EqualFunc(outbox.entries[0].Pending, want, slices.Equal) {
+		t.Fatalf("entries = %+v, want pending %v", outbox.entries, want)
+	}
+	if entry := outbox.entries[0]; entry.Attempts != 1 || entry.LastError == "" {
+		t.Errorf("entry = %+v, want 1 attempt with last error", entry)
+	}
+
+	// 第二轮: 邮件仍失败，只重发失败的目标
+	onebot.setFailing()
+	email.setFailing("b@example.com")
+	if err := outbox.Deliver(ctx, dispatcher, 0); err == nil {
+		t.Fatal("Deliver() error = nil, want email error")
+	}
+	if calls := onebot.takeCalls(); !slices.EqualFunc(calls, [][]string{{"group:2"}}, slices.Equal) {
+		t.Errorf("onebot calls = %v, want [[group:2]]", calls)
+	}
+	if calls := email.takeCalls(); !slices.EqualFunc(calls, [][]string{{"b@example.com"}}, slices.Equal) {
+		t.Errorf("email calls = %v, want [[b@example.com]]", calls)
+	}
+	want = map[string][]string{"email": {"b@example.com"}}
+	if !maps.EqualFunc(outbox.entries[0].Pending, want, slices.Equal) {
+		t.Fatalf("pending = %v, want %v", outbox.entries[0].Pending, want)
+	}
+
+	// 第三轮: 全部送达后移除
+	email.setFailing()
+	if err := outbox.Deliver(ctx, dispatcher, 0); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if calls := onebot.takeCalls(); len(calls) != 0 {
+		t.Errorf("onebot calls = %v, want none", calls)
+	}
+	if outbox, err = OpenOutbox(path); err != nil {
+		t.Fatal(err)
+	}
+	if outbox.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", outbox.Len())
+	}
+}
+
+func TestOutboxDeliverDrops(t *testing.T) {
+	tests := []struct {
+		name     string
+		channels []string
+		age      time.Duration
+		maxAge   time.Duration
+		wantLen  int
+		wantSent bool
+	}{
+		{name: "pending batch is sent", channels: []string{"email"}, age: time.Hour, wantLen: 1, wantSent: true},
+		{name: "expired batch", channels: []string{"email"}, age: 2 * time.Hour, maxAge: time.Hour},
+		{name: "channel no longer enabled", channels: []string{"onebot"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email := &fakeChannel{name: "email", err: errors.New("SMTP 连接失败")}
+			outbox, err := OpenOutbox(filepath.Join(t.TempDir(), OutboxFileName))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := outbox.Add(testBatch(), tt.channels); err != nil {
+				t.Fatal(err)
+			}
+			outbox.entries[0].CreatedAt = time.Now().Add(-tt.age)
+
+			err = outbox.Deliver(context.Background(), NewDispatcher(email), tt.maxAge)
+			if (err != nil) != tt.wantSent {
+				t.Errorf("Deliver() error = %v", err)
+			}
+			if sent := len(email.takeCalls()) > 0; sent != tt.wantSent {
+				t.Errorf("sent = %v, want %v", sent, tt.wantSent)
+			}
+			if outbox.Len() != tt.wantLen {
+				t.Errorf("Len() = %d, want %d", outbox.Len(), tt.wantLen)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// Segment OneBot 消息段，如 {"type":"text","data":{"text":"..."}}
+type Segment struct {
+	Type string            `json:"type"`
+	Data map[string]string `json:"data"`
+}
+
+// Message 由消息段组成的 OneBot 消息
+type Message []Segment
+
+// TextSegment 纯文本消息段
+func TextSegment(text string) Segment {
+	return Segment{Type: "text", Data: map[string]string{"text": text}}
+}
+
+// AtSegment @ 指定 QQ 号的消息段，仅在群消息中生效
+func AtSegment(userID string) Segment {
+	return Segment{Type: "at", Data: map[string]string{"qq": userID}}
+}
+
+// ImageSegment 以 base64:// 内联图片的消息段，不依赖 OneBot 实现能访问本机文件
+func ImageSegment(data []byte) Segment {
+	return Segment{Type: "image", Data: map[string]string{"file": "base64://" + base64.StdEncoding.EncodeToString(data)}}
+}
+
+// TextMessage 由单个文本段组成的消息
+func TextMessage(text string) Message {
+	return Message{TextSegment(text)}
+}
+
+// PlainText 返回消息的纯文本形式，@ 段显示为 @QQ号，图片显示为 [图片]
+func (m Message) PlainText() string {
+	var b strings.Builder
+	for _, seg := range m {
+		switch seg.Type {
+		case "text":
+			b.WriteString(seg.Data["text"])
+		case "at":
+			b.WriteString("@" + seg.Data["qq"])
+		case "image":
+			b.WriteString("[图片]")
+		}
+	}
+	return b.String()
+}
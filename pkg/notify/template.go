@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/W1ndys/easy-qfnu-xk-monitor/pkg/jwxt"
+)
+
+// 消息格式，不同渠道使用对应格式的模板
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// templateFuncs 模板中可用的函数
+var templateFuncs = map[string]any{
+	// default 值为空白时返回 fallback: {{ default "未知" .Course.Kcmc }}
+	"default": func(fallback, value string) string { return nonEmpty(value, fallback) },
+	// module 选课模块标识转中文名称
+	"module": jwxt.ModuleName,
+}
+
+// executor 统一 text/template 与 html/template 的执行接口
+type executor interface {
+	Execute(w *bytes.Buffer, data any) error
+}
+
+type textExecutor struct{ t *texttemplate.Template }
+
+func (e textExecutor) Execute(w *bytes.Buffer, data any) error { return e.t.Execute(w, data) }
+
+type htmlExecutor struct{ t *htmltemplate.Template }
+
+func (e htmlExecutor) Execute(w *bytes.Buffer, data any) error { return e.t.Execute(w, data) }
+
+// Renderer 按格式将一批事件渲染为消息文本。模板以 Batch 为数据，
+// HTML 格式使用 html/template 自动转义，其余格式使用 text/template。
+type Renderer struct {
+	templates map[string]executor
+}
+
+// NewRenderer 创建渲染器。paths 按格式指定模板文件，未指定的格式使用内置默认模板。
+func NewRenderer(paths map[string]string) (*Renderer, error) {
+	r := &Renderer{templates: make(map[string]executor)}
+	for _, format := range []string{FormatText, FormatMarkdown, FormatHTML} {
+		name := format + ".tmpl"
+		content, err := defaultTemplates.ReadFile("templates/" + name)
+		if err != nil {
+			return nil, fmt.Errorf("读取内置模板[%s]失败: %w", name, err)
+		}
+		if path := strings.TrimSpace(paths[format]); path != "" {
+			if content, err = os.ReadFile(path); err != nil {
+				return nil, fmt.Errorf("读取 %s 模板失败: %w", format, err)
+			}
+			name = filepath.Base(path)
+		}
+
+		exec, err := parseTemplate(format, name, string(content))
+		if err != nil {
+			return nil, fmt.Errorf("解析 %s 模板失败: %w", format, err)
+		}
+		r.templates[format] = exec
+	}
+	return r, nil
+}
+
+func parseTemplate(format, name, content string) (executor, error) {
+	if format == FormatHTML {
+		t, err := htmltemplate.New(name).Funcs(templateFuncs).Parse(content)
+		return htmlExecutor{t}, err
+	}
+	t, err := texttemplate.New(name).Funcs(templateFuncs).Parse(content)
+	return textExecutor{t}, err
+}
+
+var defaultRenderer = func() *Renderer {
+	r, err := NewRenderer(nil)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}()
+
+// DefaultRenderer 返回使用内置模板的渲染器
+func DefaultRenderer() *Renderer {
+	return defaultRenderer
+}
+
+// Render 按格式渲染一批事件
+func (r *Renderer) Render(format string, batch Batch) (string, error) {
+	exec, ok := r.templates[format]
+	if !ok {
+		return "", fmt.Errorf("不支持的消息格式: %s", format)
+	}
+	var buf bytes.Buffer
+	if err := exec.Execute(&buf, batch); err != nil {
+		return "", fmt.Errorf("渲染 %s 模板失败: %w", format, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OneBotTransport OneBot v11 API 的调用方式。实现需支持并发调用。
+type OneBotTransport interface {
+	// Call 调用 API，返回响应中的 data 字段
+	Call(ctx context.Context, action string, params any) (json.RawMessage, error)
+	// Close 释放连接
+	Close() error
+}
+
+// onebotResponse OneBot API 响应，WebSocket 模式下附带请求时的 echo
+type onebotResponse struct {
+	Status  string          `json:"status"`
+	RetCode int             `json:"retcode"`
+	Message string          `json:"message"`
+	Wording string          `json:"wording"`
+	Data    json.RawMessage `json:"data"`
+	Echo    json.RawMessage `json:"echo,omitempty"`
+}
+
+// err 将失败响应转换为 *APIError
+func (r *onebotResponse) err() error {
+	if r.RetCode == 0 || r.Status == "ok" {
+		return nil
+	}
+	message := r.Message
+	if message == "" {
+		message = r.Wording
+	}
+	return &APIError{RetCode: r.RetCode, Message: message}
+}
+
+// APIError OneBot API 返回的失败响应
+type APIError struct {
+	RetCode int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("OneBot 返回失败: retcode=%d, message=%s", e.RetCode, e.Message)
+}
+
+// StatusError OneBot HTTP 服务返回的非 2xx 状态码
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("OneBot 响应异常: %d", e.StatusCode)
+}
+
+// HTTPTransport 通过 HTTP POST 调用 OneBot API
+type HTTPTransport struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewHTTPTransport 创建 HTTP 调用方式，url 为 OneBot HTTP 服务地址
+func NewHTTPTransport(url, token string, client *http.Client) *HTTPTransport {
+	return &HTTPTransport{
+		url:    strings.TrimRight(strings.TrimSpace(url), "/"),
+		token:  strings.TrimSpace(token),
+		client: client,
+	}
+}
+
+// Call 调用 OneBot HTTP API
+func (t *HTTPTransport) Call(ctx context.Context, action string, params any) (json.RawMessage, error) {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("序列化 OneBot 请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url+"/"+action, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建 OneBot 请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 OneBot 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	var result onebotResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, nil
+	}
+	if err := result.err(); err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// Close HTTP 调用方式无需释放资源
+func (t *HTTPTransport) Close() error {
+	return nil
+}
@@ -0,0 +1,460 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsCallTimeout 调用未设置截止时间时的默认超时，包含等待连接建立的时间
+	wsCallTimeout = 10 * time.Second
+	// wsWriteTimeout 单帧写入超时
+	wsWriteTimeout = 5 * time.Second
+	// wsMinBackoff / wsMaxBackoff 正向 WebSocket 断线重连的退避区间
+	wsMinBackoff = time.Second
+	wsMaxBackoff = 30 * time.Second
+)
+
+var (
+	// ErrWSNotConnected 调用超时前 WebSocket 仍未连接
+	ErrWSNotConnected = errors.New("OneBot WebSocket 未连接")
+	// ErrWSClosed WebSocket 调用方式已关闭
+	ErrWSClosed = errors.New("OneBot WebSocket 已关闭")
+)
+
+// wsRequest OneBot v11 WebSocket API 请求
+type wsRequest struct {
+	Action string `json:"action"`
+	Params any    `json:"params"`
+	Echo   string `json:"echo"`
+}
+
+// wsPeer 一条已建立的 WebSocket 连接，按 echo 将响应分发给等待中的调用
+type wsPeer struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan *onebotResponse
+	done    chan struct{}
+	err     error
+}
+
+func newWSPeer(conn *websocket.Conn) *wsPeer {
+	return &wsPeer{
+		conn:    conn,
+		pending: make(map[string]chan *onebotResponse),
+		done:    make(chan struct{}),
+	}
+}
+
+var wsEchoSeq atomic.Uint64
+
+// call 发送请求并等待 echo 相同的响应
+func (p *wsPeer) call(ctx context.Context, action string, params any) (json.RawMessage, error) {
+	echo := "qxk-" + strconv.FormatUint(wsEchoSeq.Add(1), 10)
+	ch := make(chan *onebotResponse, 1)
+
+	p.mu.Lock()
+	if p.err != nil {
+		err := p.err
+		p.mu.Unlock()
+		return nil, err
+	}
+	p.pending[echo] = ch
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, echo)
+		p.mu.Unlock()
+	}()
+
+	payload, err := json.Marshal(wsRequest{Action: action, Params: params, Echo: echo})
+	if err != nil {
+		return nil, fmt.Errorf("序列化 OneBot 请求失败: %w", err)
+	}
+	p.writeMu.Lock()
+	_ = p.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	err = p.conn.WriteMessage(websocket.TextMessage, payload)
+	p.writeMu.Unlock()
+	if err != nil {
+		p.close(fmt.Errorf("发送 OneBot 请求失败: %w", err))
+		return nil, fmt.Errorf("发送 OneBot 请求失败: %w", err)
+	}
+
+	select {
+	case resp := <-ch:
+		if err := resp.err(); err != nil {
+			return nil, err
+		}
+		return resp.Data, nil
+	case <-p.done:
+		return nil, p.closeErr()
+	case <-ctx.Done():
+		return nil, fmt.Errorf("等待 OneBot 响应超时[%s]: %w", action, ctx.Err())
+	}
+}
+
+// readLoop 持续读取连接，响应按 echo 分发，其余帧（事件、心跳）交给 onEvent。
+// 连接出错时关闭 peer 并返回。
+func (p *wsPeer) readLoop(onEvent func([]byte)) {
+	for {
+		_, data, err := p.conn.ReadMessage()
+		if err != nil {
+			p.close(fmt.Errorf("OneBot WebSocket 连接断开: %w", err))
+			return
+		}
+
+		var resp onebotResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			log.Printf("[WARN] 无法解析 OneBot WebSocket 消息: %v", err)
+			continue
+		}
+		if len(resp.Echo) == 0 {
+			if onEvent != nil {
+				onEvent(data)
+			}
+			continue
+		}
+
+		echo := decodeEcho(resp.Echo)
+		p.mu.Lock()
+		ch, ok := p.pending[echo]
+		p.mu.Unlock()
+		if ok {
+			ch <- &resp
+		}
+	}
+}
+
+// decodeEcho echo 原样返回，字符串以外的类型按原始 JSON 比较
+func decodeEcho(raw json.RawMessage) string {
+	var echo string
+	if err := json.Unmarshal(raw, &echo); err == nil {
+		return echo
+	}
+	return string(raw)
+}
+
+func (p *wsPeer) close(err error) {
+	p.mu.Lock()
+	if p.err != nil {
+		p.mu.Unlock()
+		return
+	}
+	p.err = err
+	close(p.done)
+	p.mu.Unlock()
+	_ = p.conn.Close()
+}
+
+func (p *wsPeer) closeErr() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func (p *wsPeer) closed() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// wsHub 保存当前可用的连接，供调用方等待连接建立
+type wsHub struct {
+	mu      sync.Mutex
+	peer    *wsPeer
+	changed chan struct{}
+	closed  bool
+}
+
+func newWSHub() *wsHub {
+	return &wsHub{changed: make(chan struct{})}
+}
+
+// set 替换当前连接并唤醒等待者，返回被替换的旧连接
+func (h *wsHub) set(peer *wsPeer) *wsPeer {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	old := h.peer
+	h.peer = peer
+	close(h.changed)
+	h.changed = make(chan struct{})
+	return old
+}
+
+// clear 当前连接仍为 peer 时将其移除
+func (h *wsHub) clear(peer *wsPeer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.peer == peer {
+		h.peer = nil
+	}
+}
+
+// shutdown 关闭 hub 并返回最后的连接
+func (h *wsHub) shutdown() *wsPeer {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	peer := h.peer
+	h.peer = nil
+	close(h.changed)
+	h.changed = make(chan struct{})
+	return peer
+}
+
+// wait 等待可用连接，ctx 结束前一直未连接时返回 ErrWSNotConnected
+func (h *wsHub) wait(ctx context.Context) (*wsPeer, error) {
+	for {
+		h.mu.Lock()
+		peer, changed, closed := h.peer, h.changed, h.closed
+		h.mu.Unlock()
+
+		if closed {
+			return nil, ErrWSClosed
+		}
+		if peer != nil && !peer.closed() {
+			return peer, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ErrWSNotConnected
+		case <-time.After(100 * time.Millisecond):
+			// peer 断开时不会触发 changed，定期重新检查
+		}
+	}
+}
+
+// call 在当前连接上调用 API，未设置截止时间时使用 wsCallTimeout
+func (h *wsHub) call(ctx context.Context, action string, params any) (json.RawMessage, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, wsCallTimeout)
+		defer cancel()
+	}
+	peer, err := h.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return peer.call(ctx, action, params)
+}
+
+// WSTransport 正向 WebSocket：连接 OneBot 实现的 WebSocket 服务，断线后自动重连
+type WSTransport struct {
+	eventDispatcher
+	url    string
+	header http.Header
+	dialer *websocket.Dialer
+	hub    *wsHub
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWSTransport 创建正向 WebSocket 调用方式并在后台建立连接，url 形如 ws://127.0.0.1:3001
+func NewWSTransport(url, token string) *WSTransport {
+	header := http.Header{}
+	if token = strings.TrimSpace(token); token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &WSTransport{
+		url:    strings.TrimSpace(url),
+		header: header,
+		dialer: &websocket.Dialer{
+			Proxy:            http.ProxyFromEnvironment,
+			HandshakeTimeout: 10 * time.Second,
+		},
+		hub:    newWSHub(),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	t.wg.Add(1)
+	go t.run()
+	return t
+}
+
+// run 维持连接，断开后按指数退避重连
+func (t *WSTransport) run() {
+	defer t.wg.Done()
+	backoff := wsMinBackoff
+	for {
+		conn, resp, err := t.dialer.DialContext(t.ctx, t.url, t.header)
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+		if err != nil {
+			if t.ctx.Err() != nil {
+				return
+			}
+			log.Printf("[WARN] 连接 OneBot WebSocket 失败: %v，%s 后重试", err, backoff)
+			if !sleepOrDone(t.ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, wsMaxBackoff)
+			continue
+		}
+
+		log.Printf("[INFO] 已连接 OneBot WebSocket: %s", t.url)
+		backoff = wsMinBackoff
+		peer := newWSPeer(conn)
+		t.hub.set(peer)
+		go func() {
+			select {
+			case <-t.ctx.Done():
+				peer.close(ErrWSClosed)
+			case <-peer.done:
+			}
+		}()
+		peer.readLoop(t.dispatch)
+		t.hub.clear(peer)
+
+		if t.ctx.Err() != nil {
+			return
+		}
+		log.Printf("[WARN] %v，%s 后重连", peer.closeErr(), backoff)
+		if !sleepOrDone(t.ctx, backoff) {
+			return
+		}
+	}
+}
+
+// Call 调用 OneBot API，连接断开时等待重连
+func (t *WSTransport) Call(ctx context.Context, action string, params any) (json.RawMessage, error) {
+	return t.hub.call(ctx, action, params)
+}
+
+// Close 断开连接并停止重连
+func (t *WSTransport) Close() error {
+	t.cancel()
+	if peer := t.hub.shutdown(); peer != nil {
+		peer.close(ErrWSClosed)
+	}
+	t.wg.Wait()
+	return nil
+}
+
+// ReverseWSTransport 反向 WebSocket：监听地址，等待 OneBot 实现连接。
+// 重连由 OneBot 实现负责，新连接会替换旧连接。
+type ReverseWSTransport struct {
+	eventDispatcher
+	token    string
+	upgrader websocket.Upgrader
+	hub      *wsHub
+	server   *http.Server
+}
+
+// NewReverseWSTransport 创建反向 WebSocket 调用方式并开始监听 addr（如 :6700），接受任意路径的连接
+func NewReverseWSTransport(addr, token string) (*ReverseWSTransport, error) {
+	t := &ReverseWSTransport{
+		token: strings.TrimSpace(token),
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+		hub: newWSHub(),
+	}
+	t.server = &http.Server{
+		Addr:              addr,
+		Handler:           http.HandlerFunc(t.serveWS),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("监听反向 WebSocket 地址失败: %w", err)
+	}
+	go func() {
+		if err := t.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[ERROR] 反向 WebSocket 服务异常退出: %v", err)
+		}
+	}()
+	log.Printf("[INFO] 反向 WebSocket 监听于 %s，等待 OneBot 连接", ln.Addr())
+	return t, nil
+}
+
+func (t *ReverseWSTransport) serveWS(w http.ResponseWriter, r *http.Request) {
+	if !t.authorized(r) {
+		log.Printf("[WARN] 拒绝未授权的反向 WebSocket 连接: %s", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if role := r.Header.Get("X-Client-Role"); role != "" && !strings.EqualFold(role, "Universal") && !strings.EqualFold(role, "API") {
+		http.Error(w, "unsupported client role", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := t.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("[WARN] 反向 WebSocket 握手失败: %v", err)
+		return
+	}
+
+	peer := newWSPeer(conn)
+	if old := t.hub.set(peer); old != nil {
+		old.close(errors.New("OneBot 建立了新的反向 WebSocket 连接"))
+	}
+	log.Printf("[INFO] OneBot 已通过反向 WebSocket 连接: %s (self_id=%s)", r.RemoteAddr, r.Header.Get("X-Self-ID"))
+
+	peer.readLoop(t.dispatch)
+	t.hub.clear(peer)
+	log.Printf("[WARN] %v", peer.closeErr())
+}
+
+// authorized 校验 Authorization 头或 access_token 查询参数
+func (t *ReverseWSTransport) authorized(r *http.Request) bool {
+	if t.token == "" {
+		return true
+	}
+	auth := r.Header.Get("Authorization")
+	for _, prefix := range []string{"Bearer ", "Token "} {
+		if v, ok := strings.CutPrefix(auth, prefix); ok && v == t.token {
+			return true
+		}
+	}
+	return r.URL.Query().Get("access_token") == t.token
+}
+
+// Call 调用 OneBot API，尚未连接时等待 OneBot 连接
+func (t *ReverseWSTransport) Call(ctx context.Context, action string, params any) (json.RawMessage, error) {
+	return t.hub.call(ctx, action, params)
+}
+
+// Close 关闭当前连接并停止监听
+func (t *ReverseWSTransport) Close() error {
+	if peer := t.hub.shutdown(); peer != nil {
+		peer.close(ErrWSClosed)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return t.server.Shutdown(ctx)
+}
+
+// sleepOrDone 等待 d，ctx 结束时返回 false
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,310 @@
+package webvpn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultGatewayURL = "https://webvpn.qfnu.edu.cn"
+
+	loginPath   = "/login"
+	doLoginPath = "/do-login"
+
+	// 网关登录票据 Cookie 名前缀，完整名称为前缀 + 网关主机名（点替换为下划线）
+	ticketCookiePrefix = "wengine_vpn_ticket"
+)
+
+var (
+	// ErrNotLoggedIn 表示 WebVPN 票据缺失或已失效。
+	ErrNotLoggedIn = errors.New("WebVPN 未登录或票据已失效")
+
+	captchaIDPattern = regexp.MustCompile(`name="captcha_id"\s+value="([^"]*)"`)
+)
+
+// Config WebVPN 配置
+type Config struct {
+	GatewayURL string        // 网关地址，默认 https://webvpn.qfnu.edu.cn
+	Username   string        // 门户账号
+	Password   string        // 门户密码
+	Ticket     string        // 已登录浏览器中的 wengine_vpn_ticket，设置后跳过门户登录
+	Timeout    time.Duration // 门户登录超时，默认 30s
+}
+
+// Transport 将发往校内主机的请求改写为 WebVPN 网关 URL，
+// 并把网关返回的重定向地址还原为原始 URL，使上层代码感知不到 WebVPN 的存在。
+type Transport struct {
+	cfg     Config
+	gateway *url.URL
+	codec   *urlCodec
+	jar     http.CookieJar // 仅保存网关域名下的 Cookie（登录票据）
+
+	mu   sync.Mutex
+	base http.RoundTripper
+
+	// loginMu 串行化门户登录，避免并发请求同时发现票据失效时重复登录
+	loginMu sync.Mutex
+}
+
+// New 创建 WebVPN 传输层，需通过 Wrap 接入 HTTP 客户端并调用 Login 登录门户。
+func New(cfg Config) (*Transport, error) {
+	if cfg.GatewayURL == "" {
+		cfg.GatewayURL = DefaultGatewayURL
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	gateway, err := url.Parse(strings.TrimRight(strings.TrimSpace(cfg.GatewayURL), "/"))
+	if err != nil || gateway.Host == "" {
+		return nil, fmt.Errorf("WebVPN 网关地址格式错误: %s", cfg.GatewayURL)
+	}
+	codec, err := newURLCodec(gateway)
+	if err != nil {
+		return nil, err
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建 WebVPN CookieJar 失败: %w", err)
+	}
+
+	t := &Transport{
+		cfg:     cfg,
+		gateway: gateway,
+		codec:   codec,
+		jar:     jar,
+		base:    http.DefaultTransport,
+	}
+	if cfg.Ticket != "" {
+		t.setTicket(cfg.Ticket)
+	}
+	return t, nil
+}
+
+// Wrap 以 base 作为底层传输层，返回改写 URL 后的传输层（即 t 本身）。
+func (t *Transport) Wrap(base http.RoundTripper) http.RoundTripper {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if base != nil {
+		t.base = base
+	}
+	return t
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.baseTransport()
+	if strings.EqualFold(req.URL.Host, t.gateway.Host) {
+		return base.RoundTrip(req)
+	}
+
+	ticket := t.ticket()
+	resp, err := t.roundTripViaGateway(base, req)
+	if !errors.Is(err, ErrNotLoggedIn) || t.cfg.Username == "" || !canReplay(req) {
+		return resp, err
+	}
+
+	// 票据失效时用门户账号重新登录并重放一次请求
+	if loginErr := t.relogin(req.Context(), ticket); loginErr != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotLoggedIn, loginErr)
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	return t.roundTripViaGateway(base, req)
+}
+
+func (t *Transport) roundTripViaGateway(base http.RoundTripper, req *http.Request) (*http.Response, error) {
+	original := req.URL
+	target := t.codec.encode(original)
+
+	outReq := req.Clone(req.Context())
+	outReq.URL = target
+	outReq.Host = target.Host
+	for _, ck := range t.jar.Cookies(target) {
+		outReq.AddCookie(ck)
+	}
+
+	resp, err := base.RoundTrip(outReq)
+	if err != nil {
+		return nil, err
+	}
+	if cookies := resp.Cookies(); len(cookies) > 0 {
+		t.jar.SetCookies(target, cookies)
+	}
+
+	if location := resp.Header.Get("Location"); location != "" {
+		locURL, err := target.Parse(location)
+		if err == nil {
+			if t.isLoginPage(locURL) {
+				resp.Body.Close()
+				return nil, ErrNotLoggedIn
+			}
+			if decoded, ok := t.codec.decode(locURL); ok {
+				resp.Header.Set("Location", decoded.String())
+			}
+		}
+	}
+
+	resp.Request = req
+	return resp, nil
+}
+
+// EnsureLogin 已持有票据（例如配置了 Ticket）时直接返回，否则登录门户。
+func (t *Transport) EnsureLogin(ctx context.Context) error {
+	t.loginMu.Lock()
+	defer t.loginMu.Unlock()
+	if t.hasTicket() {
+		return nil
+	}
+	return t.login(ctx)
+}
+
+// Login 使用门户账号密码登录 WebVPN 获取票据。
+func (t *Transport) Login(ctx context.Context) error {
+	t.loginMu.Lock()
+	defer t.loginMu.Unlock()
+	return t.login(ctx)
+}
+
+// relogin 在票据失效后重新登录门户，stale 为失效请求携带的票据。
+// 等待登录锁期间其他请求可能已换到新票据，此时直接复用。
+func (t *Transport) relogin(ctx context.Context, stale string) error {
+	t.loginMu.Lock()
+	defer t.loginMu.Unlock()
+	if current := t.ticket(); current != "" && current != stale {
+		return nil
+	}
+	log.Printf("[WARN] WebVPN 票据已失效，正在重新登录门户")
+	return t.login(ctx)
+}
+
+func (t *Transport) login(ctx context.Context) error {
+	if t.cfg.Username == "" || t.cfg.Password == "" {
+		return fmt.Errorf("%w: 未配置 WebVPN 账号密码或票据", ErrNotLoggedIn)
+	}
+
+	client := &http.Client{
+		Transport: t.baseTransport(),
+		Jar:       t.jar,
+		Timeout:   t.cfg.Timeout,
+	}
+
+	// 1. 访问登录页获取 captcha_id 与初始 Cookie
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.gateway.String()+loginPath, nil)
+	if err != nil {
+		return fmt.Errorf("创建 WebVPN 登录页请求失败: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("访问 WebVPN 登录页失败: %w", err)
+	}
+	page, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("读取 WebVPN 登录页失败: %w", err)
+	}
+
+	captchaID := ""
+	if match := captchaIDPattern.FindSubmatch(page); match != nil {
+		captchaID = string(match[1])
+	}
+
+	// 2. 提交本地账号登录
+	form := url.Values{
+		"auth_type":   {"local"},
+		"username":    {t.cfg.Username},
+		"sms_code":    {""},
+		"password":    {t.cfg.Password},
+		"captcha":     {""},
+		"needCaptcha": {"false"},
+		"captcha_id":  {captchaID},
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, t.gateway.String()+doLoginPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("创建 WebVPN 登录请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Referer", t.gateway.String()+loginPath)
+
+	resp, err = client.Do(req)
+	if err != nil {
+		return fmt.Errorf("WebVPN 登录请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("解析 WebVPN 登录响应失败: %w", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("WebVPN 登录失败: %s %s", result.Error, result.Message)
+	}
+	if !t.hasTicket() {
+		return fmt.Errorf("WebVPN 登录成功但未返回票据 Cookie")
+	}
+
+	log.Printf("[INFO] WebVPN 门户登录成功: %s", t.gateway.Host)
+	return nil
+}
+
+func (t *Transport) baseTransport() http.RoundTripper {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.base
+}
+
+func (t *Transport) ticketCookieName() string {
+	return ticketCookiePrefix + strings.ReplaceAll(t.gateway.Hostname(), ".", "_")
+}
+
+func (t *Transport) setTicket(ticket string) {
+	t.jar.SetCookies(t.gateway, []*http.Cookie{
+		{Name: t.ticketCookieName(), Value: ticket, Path: "/"},
+		{Name: ticketCookiePrefix, Value: ticket, Path: "/"},
+	})
+}
+
+func (t *Transport) hasTicket() bool {
+	return t.ticket() != ""
+}
+
+// ticket 返回当前持有的全部票据 Cookie 值，用于判断票据是否已更换；没有票据时返回空字符串
+func (t *Transport) ticket() string {
+	var values []string
+	for _, ck := range t.jar.Cookies(t.gateway) {
+		if strings.HasPrefix(ck.Name, ticketCookiePrefix) && ck.Value != "" {
+			values = append(values, ck.Name+"="+ck.Value)
+		}
+	}
+	return strings.Join(values, "; ")
+}
+
+// isLoginPage 判断重定向目标是否为 WebVPN 门户登录页
+func (t *Transport) isLoginPage(u *url.URL) bool {
+	return strings.EqualFold(u.Host, t.gateway.Host) && u.Path == loginPath
+}
+
+func canReplay(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
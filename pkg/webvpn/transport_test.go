@@ -0,0 +1,67 @@
+package webvpn
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRoundTripReloginOnce(t *testing.T) {
+	var logins atomic.Int32
+	var gateway *httptest.Server
+	ticketName := ticketCookiePrefix + "127_0_0_1"
+	gateway = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case loginPath:
+			io.WriteString(w, `<input name="captcha_id" value="cid">`)
+		case doLoginPath:
+			logins.Add(1)
+			http.SetCookie(w, &http.Cookie{Name: ticketName, Value: "fresh", Path: "/"})
+			io.WriteString(w, `{"success": true}`)
+		default:
+			if ck, err := r.Cookie(ticketName); err != nil || ck.Value != "fresh" {
+				http.Redirect(w, r, gateway.URL+loginPath, http.StatusFound)
+				return
+			}
+			io.WriteString(w, "ok")
+		}
+	}))
+	defer gateway.Close()
+
+	transport, err := New(Config{GatewayURL: gateway.URL, Username: "u", Password: "p", Ticket: "stale"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{
+		Transport: transport.Wrap(gateway.Client().Transport),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	// 并发请求同时发现票据失效时只应登录一次
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get("http://zhjw.qfnu.edu.cn/jsxsd/")
+			if err != nil {
+				t.Errorf("Get() error = %v", err)
+				return
+			}
+			defer resp.Body.Close()
+			if body, _ := io.ReadAll(resp.Body); string(body) != "ok" {
+				t.Errorf("body = %q, want ok", body)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := logins.Load(); n != 1 {
+		t.Errorf("门户登录次数 = %d, want 1", n)
+	}
+}
@@ -0,0 +1,120 @@
+package webvpn
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 网瑞达 WebVPN 使用固定的 AES-128-CFB 密钥与 IV 加密目标主机名
+const (
+	defaultKey = "wrdvpnisthebest!"
+	defaultIV  = "wrdvpnisthebest!"
+)
+
+// urlCodec 负责原始 URL 与 WebVPN 网关 URL 之间的转换。
+//
+// 网关 URL 格式: <网关>/<协议>[-<端口>]/<hex(IV)><hex(AES-CFB(主机名))>/<路径>?<查询>
+// 例如 http://zhjw.qfnu.edu.cn/jsxsd/ 对应 https://webvpn.qfnu.edu.cn/http/77726476706e69737468656265737421.../jsxsd/
+type urlCodec struct {
+	gateway *url.URL
+	block   cipher.Block
+	iv      []byte
+}
+
+func newURLCodec(gateway *url.URL) (*urlCodec, error) {
+	block, err := aes.NewCipher([]byte(defaultKey))
+	if err != nil {
+		return nil, fmt.Errorf("创建 WebVPN 密钥失败: %w", err)
+	}
+	return &urlCodec{gateway: gateway, block: block, iv: []byte(defaultIV)}, nil
+}
+
+// encode 将原始 URL 转换为网关 URL
+func (c *urlCodec) encode(u *url.URL) *url.URL {
+	host := u.Hostname()
+	encrypted := make([]byte, len(host))
+	cipher.NewCFBEncrypter(c.block, c.iv).XORKeyStream(encrypted, []byte(host))
+
+	scheme := u.Scheme
+	if port := u.Port(); port != "" && port != defaultPort(u.Scheme) {
+		scheme += "-" + port
+	}
+
+	path, rawPath := u.Path, u.EscapedPath()
+	if path == "" {
+		path, rawPath = "/", "/"
+	}
+
+	prefix := "/" + scheme + "/" + hex.EncodeToString(c.iv) + hex.EncodeToString(encrypted)
+	rewritten := *c.gateway
+	rewritten.Path = prefix + path
+	rewritten.RawPath = prefix + rawPath
+	rewritten.RawQuery = u.RawQuery
+	rewritten.Fragment = ""
+	return &rewritten
+}
+
+// decode 将网关 URL 还原为原始 URL，不是网关格式时返回 false
+func (c *urlCodec) decode(u *url.URL) (*url.URL, bool) {
+	if !strings.EqualFold(u.Host, c.gateway.Host) {
+		return nil, false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(u.EscapedPath(), "/"), "/", 3)
+	if len(parts) < 2 {
+		return nil, false
+	}
+
+	scheme, port, _ := strings.Cut(parts[0], "-")
+	if scheme != "http" && scheme != "https" {
+		return nil, false
+	}
+	if port != "" {
+		if _, err := strconv.Atoi(port); err != nil {
+			return nil, false
+		}
+	}
+
+	ivHex := hex.EncodeToString(c.iv)
+	if !strings.HasPrefix(parts[1], ivHex) {
+		return nil, false
+	}
+	encrypted, err := hex.DecodeString(strings.TrimPrefix(parts[1], ivHex))
+	if err != nil || len(encrypted) == 0 {
+		return nil, false
+	}
+	host := make([]byte, len(encrypted))
+	cipher.NewCFBDecrypter(c.block, c.iv).XORKeyStream(host, encrypted)
+
+	original := &url.URL{
+		Scheme:   scheme,
+		Host:     string(host),
+		RawQuery: u.RawQuery,
+	}
+	if port != "" {
+		original.Host += ":" + port
+	}
+	path := "/"
+	if len(parts) == 3 {
+		path += parts[2]
+	}
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		original.Path = unescaped
+		original.RawPath = path
+	} else {
+		original.Path = path
+	}
+	return original, true
+}
+
+func defaultPort(scheme string) string {
+	if scheme == "https" {
+		return "443"
+	}
+	return "80"
+}